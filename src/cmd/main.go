@@ -1,13 +1,17 @@
 package main
 
 import (
-	_ "fmt"
+	"flag"
+	"fmt"
 
 	"os"
-	_ "strings"
+	"strings"
 
 	_ "github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/azclient"
+	"github.com/brendank310/aztui/pkg/cache"
 	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/dryrun"
 	"github.com/brendank310/aztui/pkg/logger"
 	"github.com/brendank310/aztui/pkg/resourceviews"
 
@@ -21,21 +25,44 @@ type AzTuiState struct {
 	config.Config
 }
 
-func NewAzTuiState() *AzTuiState {
+func NewAzTuiState(profile string, credential string, logLevel string) *AzTuiState {
 	// Base initialization
-	err := logger.InitLogger()
+	c, err := config.LoadConfig(config.Path())
 	if err != nil {
 		panic(err)
 	}
 
-	configPath := os.Getenv("AZTUI_CONFIG_PATH")
-	if configPath == "" {
-		configPath = os.Getenv("HOME") + "/.config/aztui.yaml"
+	if logLevel == "" {
+		logLevel = c.Log.Level
 	}
-
-	c, err := config.LoadConfig(configPath)
+	level, err := logger.ParseLevel(logLevel)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	err = logger.Init(logger.Options{
+		Path:       os.ExpandEnv(c.Log.Path),
+		Level:      level,
+		MaxSize:    int64(c.Log.MaxSizeMB) << 20,
+		MaxBackups: c.Log.MaxBackups,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logger.Info("Starting aztui", "config", config.Path(), "dryRun", dryrun.CurrentMode().String())
+
+	if profile != "" {
+		if err := config.SetProfile(profile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if credential != "" {
+		if err := config.SetCredential(credential); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
 
 	a := AzTuiState{
@@ -43,26 +70,97 @@ func NewAzTuiState() *AzTuiState {
 		Config:    c,
 	}
 
-	subscriptionList := resourceviews.NewSubscriptionListView(a.AppLayout)
-	if subscriptionList == nil {
-		panic("unable to create a subscription list")
-	}
+	a.AppLayout.ResetViews()
 
 	a.AppLayout.InputField.SetFinishedFunc(func(key tcell.Key) {
-		if a.FocusedViewIndex == 0 {
-			a.App.SetFocus(subscriptionList.List)
-		} else if a.FocusedViewIndex == 1 {
-			a.App.SetFocus(subscriptionList.ResourceGroupListView.List)
-		}
+		a.FocusView(a.FocusedViewIndex)
 	})
+	a.AppLayout.WatchConfig(config.Path())
 
 	return &a
 }
 
 func main() {
-	a := NewAzTuiState()
+	dryRun := flag.String("dry-run", "off", "preview mutating az commands and ARM requests before executing them (preview), or never execute them (strict)")
+	profile := flag.String("profile", "", "profile to start with instead of defaultProfile")
+	credential := flag.String("credential", "", "credential to sign in with instead of the profile's: "+strings.Join(config.Credentials, ", "))
+	noCache := flag.Bool("no-cache", false, "don't show cached listings while fetching them, nor cache them")
+	trace := flag.Bool("trace", false, "record ARM requests for the requests view from the start")
+	logLevel := flag.String("log-level", "", "log entries at this level and above: "+strings.Join(config.LogLevels, ", ")+" (default log.level of the configuration)")
+	flag.Parse()
+
+	mode, err := dryrun.ParseMode(*dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	dryrun.SetMode(mode)
+
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+
+	// Without AZTUI_CONFIG_PATH the configuration file is optional
+	if path := os.Getenv("AZTUI_CONFIG_PATH"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
-	if err := a.AppLayout.App.SetRoot(a.AppLayout.Grid, true).Run(); err != nil {
+	if problems := config.ValidateFile(config.Path(), resourceviews.ConfigSchema()); len(problems) > 0 {
+		fmt.Fprintln(os.Stderr, problems)
+		os.Exit(1)
+	}
+
+	if *trace {
+		azclient.SetTracing(true)
+	}
+	if *noCache {
+		cache.SetEnabled(false)
+	}
+
+	a := NewAzTuiState(*profile, *credential, *logLevel)
+
+	if err := a.AppLayout.App.SetRoot(a.AppLayout.Pages, true).Run(); err != nil {
 		panic(err)
 	}
 }
+
+// runCommand runs a command given on the command line instead of the TUI
+// and returns the exit code.
+func runCommand(args []string) int {
+	switch {
+	case len(args) >= 2 && len(args) <= 3 && args[0] == "config" && args[1] == "validate":
+		path := config.Path()
+		if len(args) == 3 {
+			path = args[2]
+		}
+
+		if problems := config.ValidateFile(path, resourceviews.ConfigSchema()); len(problems) > 0 {
+			fmt.Fprintln(os.Stderr, problems)
+			return 1
+		}
+
+		fmt.Printf("%v: ok\n", path)
+		return 0
+
+	case len(args) >= 2 && len(args) <= 3 && args[0] == "config" && args[1] == "dump":
+		path := config.Path()
+		if len(args) == 3 {
+			path = args[2]
+		}
+
+		data, err := config.Dump(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		os.Stdout.Write(data)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\nUsage:\n  aztui [flags]\n  aztui config validate [file]\n  aztui config dump [file]\n", strings.Join(args, " "))
+	return 2
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -26,47 +27,15 @@ func main() {
 		log.Fatalf("%v\n%v", rc, err)
 	}
 
-	_, err = azcli.RunAzCommand(args, func(a []string, err error) error {
-		if strings.HasPrefix(err.Error(), "ERROR: InvalidArgumentValue:") {
-			newArgs := a
-			reader := bufio.NewReader(os.Stdin) // Create a new reader
-			missingArg := strings.Split(err.Error(), "field:")[1]
-			log.Printf("Missing argument %v - Enter now: ", missingArg)
-			rg, _ := reader.ReadString('\n') // Read input until newline
-			newArgs = append(newArgs, missingArg)
-			newArgs = append(newArgs, rg)
-
-			_, e := azcli.RunAzCommand(newArgs, nil)
-			if e != nil {
-				return e
-			}
-		}
-
-		if strings.HasSuffix(err.Error(), "are required\n") {
-			newArgs := a
-			reader := bufio.NewReader(os.Stdin) // Create a new reader
-
-			extractRequiredArgs := strings.Split(err.Error(), ":")[1]
-			missingArgs := strings.Split(strings.TrimSuffix(strings.Replace(strings.Replace(extractRequiredArgs, "(", "", 1), ")", "", 1), " are required\n"), "|")
-
-			for _, arg := range missingArgs {
-				log.Printf("Enter value for %v: ", arg)
-				value, _ := reader.ReadString('\n') // Read input until newlin
-
-				newArgs = append(newArgs, strings.TrimSpace(arg))
-				newArgs = append(newArgs, strings.TrimSpace(value))
-
-				break
-			}
-
-			stdout, e := azcli.RunAzCommand(newArgs, nil)
-			if e != nil {
-				return e
-			}
-
-			log.Println(stdout)
-		}
-
-		return nil
+	reader := bufio.NewReader(os.Stdin) // Create a new reader
+	stdout, err := azcli.RunAzCommandPromptMissingArgs(args, func(arg string) (string, error) {
+		fmt.Printf("Enter value for %v: ", arg)
+		value, err := reader.ReadString('\n') // Read input until newline
+		return strings.TrimSpace(value), err
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println(stdout)
 }
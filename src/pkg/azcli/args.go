@@ -0,0 +1,111 @@
+package azcli
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+// az reports missing arguments in one of two ways, depending on whether
+// argparse or the command itself validates them:
+//
+//	the following arguments are required: --resource-group/-g, --name/-n
+//	(--name --resource-group | --ids) are required
+var requiredArgumentsRegexp = regexp.MustCompile(`the following arguments are required: (.*)`)
+var requiredAlternativesRegexp = regexp.MustCompile(`\(([^)]*)\) (?:is|are) required`)
+
+// MissingArguments returns the long form of every argument az reported as
+// missing in its error output. When az offers alternatives, the first one is
+// returned.
+func MissingArguments(stderr string) []string {
+	missing := []string{}
+
+	if m := requiredArgumentsRegexp.FindStringSubmatch(stderr); m != nil {
+		for _, arg := range strings.Split(m[1], ",") {
+			option := strings.Split(strings.TrimSpace(arg), "/")[0]
+			if strings.HasPrefix(option, "-") {
+				missing = append(missing, option)
+			}
+		}
+
+		return missing
+	}
+
+	if m := requiredAlternativesRegexp.FindStringSubmatch(stderr); m != nil {
+		alternative := strings.Split(m[1], "|")[0]
+		for _, option := range strings.Fields(alternative) {
+			if strings.HasPrefix(option, "-") {
+				missing = append(missing, option)
+			}
+		}
+	}
+
+	return missing
+}
+
+// BuildArgs returns the az arguments to invoke a command with the given
+// parameter values, keyed by the long form of the parameter. Empty values are
+// left out so az applies its own defaults.
+func BuildArgs(command Command, values map[string]string) []string {
+	args := command.Args()
+	for _, p := range command.Parameters {
+		value := strings.TrimSpace(values[p.Name])
+		if value == "" {
+			continue
+		}
+
+		args = append(args, p.Name)
+		if p.Type == ParameterTypeList {
+			args = append(args, strings.Fields(value)...)
+		} else {
+			args = append(args, value)
+		}
+	}
+
+	return args
+}
+
+// WithDefaultOutput asks az for the configured output format, JSON unless
+// configured otherwise, when args don't already select one.
+func WithDefaultOutput(args []string) []string {
+	for _, arg := range args {
+		if arg == "--output" || arg == "-o" || strings.HasPrefix(arg, "--output=") {
+			return args
+		}
+	}
+
+	output := config.Current().AzCLI.Output
+	if output == "" {
+		output = "json"
+	}
+
+	return append(append([]string{}, args...), "--output", output)
+}
+
+// WithDefaultArgs adds the arguments configured for every command to args,
+// skipping options args already sets.
+func WithDefaultArgs(args []string) []string {
+	defaults := config.Current().AzCLI.Args
+	if len(defaults) == 0 {
+		return args
+	}
+
+	present := make(map[string]bool)
+	for _, arg := range args {
+		present[strings.SplitN(arg, "=", 2)[0]] = true
+	}
+
+	result := append([]string{}, args...)
+	skipping := false
+	for _, arg := range defaults {
+		if strings.HasPrefix(arg, "-") {
+			skipping = present[strings.SplitN(arg, "=", 2)[0]]
+		}
+		if !skipping {
+			result = append(result, arg)
+		}
+	}
+
+	return result
+}
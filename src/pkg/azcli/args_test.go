@@ -0,0 +1,101 @@
+package azcli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMissingArguments(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   []string
+	}{
+		{"", []string{}},
+		{"ERROR: (ResourceGroupNotFound) Resource group 'rg' could not be found.", []string{}},
+		{
+			"usage: az vm show [-h] [--verbose] ...\nthe following arguments are required: --resource-group/-g, --name/-n\n",
+			[]string{"--resource-group", "--name"},
+		},
+		{
+			"ERROR: the following arguments are required: --location/-l",
+			[]string{"--location"},
+		},
+		{
+			"ERROR: (--name --resource-group | --ids) are required",
+			[]string{"--name", "--resource-group"},
+		},
+		{
+			"ERROR: (--ids | --name --resource-group) is required",
+			[]string{"--ids"},
+		},
+		{
+			"the following arguments are required: _subcommand",
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := MissingArguments(tt.stderr); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MissingArguments(%q) = %q, want %q", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	command := Command{
+		Name:  "create",
+		Group: "storage account",
+		Parameters: []Parameter{
+			{Name: "--name", Type: ParameterTypeString},
+			{Name: "--tags", Type: ParameterTypeList},
+			{Name: "--sku", Type: ParameterTypeEnum},
+			{Name: "--https-only", Type: ParameterTypeBool},
+		},
+	}
+	values := map[string]string{
+		"--name":       " account ",
+		"--tags":       "a=b  c=d",
+		"--sku":        "",
+		"--https-only": "true",
+	}
+
+	want := []string{"storage", "account", "create", "--name", "account", "--tags", "a=b", "c=d", "--https-only", "true"}
+	if got := BuildArgs(command, values); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestPromptMissingArgsKeepsCallerArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake az is a shell script")
+	}
+
+	// az requiring --name
+	dir := t.TempDir()
+	az := filepath.Join(dir, "az")
+	script := "#!/bin/sh\ncase \"$*\" in\n*--name*) echo '{}' ;;\n*) echo 'the following arguments are required: --name/-n' >&2; exit 2 ;;\nesac\n"
+	if err := os.WriteFile(az, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	useHistoryConfig(t, "azcli:\n  path: "+az+"\n")
+
+	// Spare capacity the arguments prompted for could be appended into
+	backing := []string{"vm", "show", "unused", "unused"}
+	args := backing[:2]
+	stdout, err := RunAzCommandPromptMissingArgs(args, func(arg string) (string, error) {
+		return "vm1", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(stdout) != "{}" {
+		t.Errorf("stdout %q", stdout)
+	}
+	if want := []string{"vm", "show", "unused", "unused"}; !reflect.DeepEqual(backing, want) {
+		t.Errorf("caller's arguments changed to %q", backing)
+	}
+}
@@ -1,63 +1,41 @@
 package azcli
 
 import (
-	"bytes"
-
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"slices"
 	"strings"
-)
+	"time"
 
-var gAzResourceCommandMap map[string]string
+	"github.com/brendank310/aztui/pkg/dryrun"
+	"github.com/brendank310/aztui/pkg/logger"
+)
 
-// If we don't have an implementation for a given command, fall back to
-// shelling out to azcli.
+// GetResourceCommands returns the commands of an az command group mapped to
+// their summaries. If we don't have an implementation for a given command,
+// fall back to shelling out to azcli.
 func GetResourceCommands(subcommand string) (map[string]string, error) {
 	resourceCommands := make(map[string]string)
-	gAzResourceCommandMap := make(map[string]string)
-	cmd := exec.Command("az", subcommand, "--help")
 
-	// Create buffers to capture stdout and stderr
-	var stdoutBuf, stderrBuf bytes.Buffer
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
-
-	// Run the command
-	err := cmd.Run()
+	catalog, err := GetCatalog()
 	if err != nil {
-		return resourceCommands, fmt.Errorf("Command execution failed with error: %v\n", err)
+		return resourceCommands, err
 	}
 
-	// Read and print stdout and stderr
-	stdoutStr := stdoutBuf.String()
-	subCommandPairs := strings.Split(stdoutStr, "Commands:")[1]
-	for _, commandPair := range strings.Split(subCommandPairs, "\n") {
-		if commandPair == "" {
-			continue
-		}
-
-		pair := strings.Split(commandPair, ":")
-		if len(pair) != 2 {
-			continue
-		}
+	group, err := catalog.Group(subcommand)
+	if err != nil {
+		return resourceCommands, err
+	}
 
-		resourceCommands[strings.TrimSpace(pair[0])] = pair[1]
-		gAzResourceCommandMap[strings.TrimSpace(pair[0])] = pair[1]
+	for _, command := range group.Commands {
+		resourceCommands[command.Name] = command.Summary
 	}
 
 	return resourceCommands, nil
 }
 
-// Function to check if a slice contains a specific string
-func contains(slice []string, item string) bool {
-	for _, v := range slice {
-		if v == item {
-			return true
-		}
-	}
-	return false
-}
-
 func RunAzCommand(args []string, handleErrorFunc func([]string, error) error) (string, error) {
 	if len(args) == 0 {
 		return "", fmt.Errorf("empty args list")
@@ -68,38 +46,112 @@ func RunAzCommand(args []string, handleErrorFunc func([]string, error) error) (s
 		return "", fmt.Errorf("empty subcommand")
 	}
 
-	// Slice to store the keys
-	var keys []string
+	stdout, stderr, err := runAzRecorded(args)
+	if err != nil {
+		if handleErrorFunc != nil {
+			handleErrorFunc(args, fmt.Errorf("%v", stderr))
+			return "", err
+		}
 
-	// Iterate over the map to get the keys
-	for key := range gAzResourceCommandMap {
-		keys = append(keys, key)
+		return "", fmt.Errorf("%v: %v", err, strings.TrimSpace(stderr))
 	}
 
-	if contains(keys, args[0]) {
-		return "", fmt.Errorf("invalid: %v not in %v", args[0], keys)
-	}
-	azcli := exec.Command("az", args...)
+	return stdout, nil
+}
 
-	// Create buffers to capture stdout and stderr
-	var stdoutBuf, stderrBuf bytes.Buffer
-	azcli.Stdout = &stdoutBuf
-	azcli.Stderr = &stderrBuf
+// runAzRecorded runs az like runAz, with the configured default arguments,
+// and records the command in the history. Mutating commands are first checked against the dry-run mode.
+func runAzRecorded(args []string) (string, string, error) {
+	args = WithDefaultArgs(args)
+	if !IsReadOnlyCommand(args) {
+		if err := dryrun.Check(dryrun.Request{CommandLine: ShellCommand(args)}); err != nil {
+			return "", err.Error(), err
+		}
+	}
 
-	// Run the command
-	err := azcli.Run()
+	start := time.Now()
+	stdout, stderr, err := runAz(context.Background(), args)
 
-	if err != nil {
-		if handleErrorFunc != nil {
-			handleErrorFunc(args, fmt.Errorf("%v", stderrBuf.String()))
-			return "", err
-		}
+	result := Result{
+		Args:     args,
+		Duration: time.Since(start),
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Err:      err,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		result.ExitCode = -1
+	}
+	if historyErr := AppendHistory(NewHistoryEntry(result, "")); historyErr != nil {
+		logger.Warn("Failed to record command history", "err", historyErr)
 	}
 
-	return stdoutBuf.String(), nil
+	return stdout, stderr, err
 }
 
+// RunAzCommandPromptMissingArgs runs an az command, asking promptUser for the
+// value of every argument az reports as missing and retrying until the
+// command either succeeds or fails for another reason.
 func RunAzCommandPromptMissingArgs(args []string, promptUser func(string) (string, error)) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty args list")
+	}
+
+	prompted := make(map[string]bool)
+	for {
+		stdout, stderr, err := runAzRecorded(args)
+		if err == nil {
+			return stdout, nil
+		}
 
-	return "", nil
+		missing := MissingArguments(stderr)
+		if len(missing) == 0 {
+			return "", fmt.Errorf("%v: %v", err, strings.TrimSpace(stderr))
+		}
+
+		for _, arg := range missing {
+			// az keeps rejecting a value we already asked for, give up
+			// rather than prompting forever
+			if prompted[arg] {
+				return "", fmt.Errorf("%v: %v", err, strings.TrimSpace(stderr))
+			}
+			prompted[arg] = true
+
+			value, err := promptUser(arg)
+			if err != nil {
+				return "", err
+			}
+
+			// Clipped so the caller's array is never written to
+			args = append(slices.Clip(args), arg, value)
+		}
+	}
+}
+
+// IsReadOnlyCommand reports whether args run an az command that only reads,
+// judged by its verb: list, show and their variants, wait, and help. Every
+// other command is assumed to change something.
+func IsReadOnlyCommand(args []string) bool {
+	verb := ""
+	for i, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			return true
+		}
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		if i == 0 && arg == "version" {
+			return true
+		}
+		verb = arg
+	}
+
+	switch verb {
+	case "list", "show", "wait":
+		return true
+	}
+	return strings.HasPrefix(verb, "list-") || strings.HasPrefix(verb, "show-")
 }
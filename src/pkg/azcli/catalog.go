@@ -0,0 +1,390 @@
+package azcli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ParameterTypeString = "string"
+	ParameterTypeBool   = "bool"
+	ParameterTypeEnum   = "enum"
+	ParameterTypeList   = "list"
+)
+
+// Arguments in this help section are accepted by every az command and are
+// not specific to the command they are listed for.
+const GlobalArgumentsGroup = "Global Arguments"
+
+type Parameter struct {
+	// Long form of the option, e.g. --resource-group
+	Name string `json:"name"`
+	// Every spelling of the option, e.g. --resource-group and -g
+	Options     []string `json:"options"`
+	Group       string   `json:"group"`
+	Required    bool     `json:"required"`
+	Type        string   `json:"type"`
+	Choices     []string `json:"choices,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Description string   `json:"description"`
+	Status      string   `json:"status,omitempty"`
+}
+
+type Command struct {
+	Name       string      `json:"name"`
+	Group      string      `json:"group"`
+	Summary    string      `json:"summary"`
+	Status     string      `json:"status,omitempty"`
+	Parameters []Parameter `json:"parameters,omitempty"`
+}
+
+type CommandGroup struct {
+	Name      string         `json:"name"`
+	Summary   string         `json:"summary"`
+	Status    string         `json:"status,omitempty"`
+	Subgroups []CommandGroup `json:"subgroups,omitempty"`
+	Commands  []Command      `json:"commands,omitempty"`
+}
+
+// Catalog holds the az command groups and commands discovered so far. Groups
+// and commands are discovered lazily and written through to an on-disk cache
+// that is keyed by the az version and installed extensions, so each help page
+// is only parsed once per az release.
+type Catalog struct {
+	mu      sync.Mutex
+	path    string
+	Version string `json:"version"`
+	// Installed extensions mapped to their versions, their commands show up
+	// in the help pages of the groups they extend
+	Extensions map[string]string        `json:"extensions,omitempty"`
+	Groups     map[string]*CommandGroup `json:"groups"`
+	Commands   map[string]*Command      `json:"commands"`
+	runHelp    func(args ...string) (string, error)
+	cacheFail  bool
+}
+
+var gCatalog *Catalog
+var gCatalogMu sync.Mutex
+
+// Path of the command, e.g. "vm start"
+func (c Command) Path() string {
+	return c.Group + " " + c.Name
+}
+
+// Arguments to pass to az to invoke the command, e.g. ["storage", "account", "show"]
+func (c Command) Args() []string {
+	return append(strings.Fields(c.Group), c.Name)
+}
+
+// Parameters of the command that are not global az arguments
+func (c Command) CommandParameters() []Parameter {
+	params := []Parameter{}
+	for _, p := range c.Parameters {
+		if p.Group != GlobalArgumentsGroup {
+			params = append(params, p)
+		}
+	}
+
+	return params
+}
+
+// Look up a parameter by any of its spellings, e.g. "-g" or "--resource-group"
+func (c Command) Parameter(option string) (Parameter, bool) {
+	for _, p := range c.Parameters {
+		for _, o := range p.Options {
+			if o == option {
+				return p, true
+			}
+		}
+	}
+
+	return Parameter{}, false
+}
+
+func (c Command) HasParameter(option string) bool {
+	_, ok := c.Parameter(option)
+	return ok
+}
+
+// GetCatalog returns the process wide catalog, loading the on-disk cache for
+// the installed az version and extensions the first time it is called.
+func GetCatalog() (*Catalog, error) {
+	gCatalogMu.Lock()
+	defer gCatalogMu.Unlock()
+
+	if gCatalog != nil {
+		return gCatalog, nil
+	}
+
+	version, err := GetAzVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCatalog(version.CLI, runAzHelp)
+	c.Extensions = version.Extensions
+	cacheDir, err := os.UserCacheDir()
+	if err == nil {
+		c.path = filepath.Join(cacheDir, "aztui", fmt.Sprintf("azcli-catalog-%v.json", version.Key()))
+		c.load()
+	}
+
+	gCatalog = c
+	return gCatalog, nil
+}
+
+func NewCatalog(version string, runHelp func(args ...string) (string, error)) *Catalog {
+	return &Catalog{
+		Version:  version,
+		Groups:   make(map[string]*CommandGroup),
+		Commands: make(map[string]*Command),
+		runHelp:  runHelp,
+	}
+}
+
+// AzVersion describes the installed az.
+type AzVersion struct {
+	// Version of the azure-cli package
+	CLI string
+	// Installed extensions mapped to their versions
+	Extensions map[string]string
+}
+
+// Key identifies the combination of az and extension versions, e.g.
+// "2.64.0-1a2b3c4d" or just "2.64.0" without extensions.
+func (v AzVersion) Key() string {
+	if len(v.Extensions) == 0 {
+		return v.CLI
+	}
+
+	names := make([]string, 0, len(v.Extensions))
+	for name := range v.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := fnv.New32a()
+	for _, name := range names {
+		fmt.Fprintf(h, "%v=%v;", name, v.Extensions[name])
+	}
+
+	return fmt.Sprintf("%v-%08x", v.CLI, h.Sum32())
+}
+
+// GetAzVersion returns the versions of the installed azure-cli package and
+// extensions.
+func GetAzVersion() (AzVersion, error) {
+	stdout, stderr, err := runAz(context.Background(), []string{"version", "--output", "json"})
+	if err != nil {
+		return AzVersion{}, fmt.Errorf("failed to get az version: %v: %v", err, strings.TrimSpace(stderr))
+	}
+
+	versions := struct {
+		CLI        string            `json:"azure-cli"`
+		Extensions map[string]string `json:"extensions"`
+	}{}
+	if err := json.Unmarshal([]byte(stdout), &versions); err != nil {
+		return AzVersion{}, fmt.Errorf("failed to parse az version output: %v", err)
+	}
+
+	if versions.CLI == "" {
+		return AzVersion{}, fmt.Errorf("az version output has no azure-cli version")
+	}
+
+	return AzVersion{CLI: versions.CLI, Extensions: versions.Extensions}, nil
+}
+
+func runAzHelp(args ...string) (string, error) {
+	stdout, stderr, err := runAz(context.Background(), append(args, "--help"))
+	if err != nil {
+		return "", fmt.Errorf("az %v --help failed: %v: %v", strings.Join(args, " "), err, strings.TrimSpace(stderr))
+	}
+
+	return stdout, nil
+}
+
+func (c *Catalog) load() {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+
+	cached := Catalog{}
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Version != c.Version {
+		return
+	}
+
+	if cached.Groups != nil {
+		c.Groups = cached.Groups
+	}
+	if cached.Commands != nil {
+		c.Commands = cached.Commands
+	}
+}
+
+// Writing the cache is best effort, a read-only cache directory only costs
+// re-running az --help on the next start.
+func (c *Catalog) save() {
+	if c.path == "" || c.cacheFail {
+		return
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		c.cacheFail = true
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		c.cacheFail = true
+		return
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		c.cacheFail = true
+		return
+	}
+
+	if err := os.Rename(tmp, c.path); err != nil {
+		c.cacheFail = true
+	}
+}
+
+// Group returns the subgroups and commands of a command group, e.g. "vm" or
+// "storage account". Command parameters are not loaded, use Command for that.
+func (c *Catalog) Group(name string) (*CommandGroup, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name = normalizeCommandPath(name)
+	if g, ok := c.Groups[name]; ok {
+		return g, nil
+	}
+
+	text, err := c.runHelp(strings.Fields(name)...)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := parseHelpDoc(text)
+	if len(doc.Commands) == 0 && len(doc.Subgroups) == 0 {
+		return nil, fmt.Errorf("%v is not an az command group", name)
+	}
+
+	g := &CommandGroup{
+		Name:    name,
+		Summary: doc.Summary,
+		Status:  doc.Status,
+	}
+
+	for _, entry := range doc.Subgroups {
+		g.Subgroups = append(g.Subgroups, CommandGroup{
+			Name:    strings.TrimSpace(name + " " + entry.Name),
+			Summary: entry.Description,
+			Status:  helpStatus(entry.Tags),
+		})
+	}
+
+	for _, entry := range doc.Commands {
+		g.Commands = append(g.Commands, Command{
+			Name:    entry.Name,
+			Group:   name,
+			Summary: entry.Description,
+			Status:  helpStatus(entry.Tags),
+		})
+	}
+
+	c.Groups[name] = g
+	c.save()
+
+	return g, nil
+}
+
+// Command returns a command with its parameters, e.g. "vm start".
+func (c *Catalog) Command(path string) (*Command, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path = normalizeCommandPath(path)
+	if cmd, ok := c.Commands[path]; ok {
+		return cmd, nil
+	}
+
+	fields := strings.Fields(path)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("%v is not an az command", path)
+	}
+
+	text, err := c.runHelp(fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := parseHelpDoc(text)
+	cmd := &Command{
+		Name:    fields[len(fields)-1],
+		Group:   strings.Join(fields[:len(fields)-1], " "),
+		Summary: doc.Summary,
+		Status:  doc.Status,
+	}
+
+	for _, group := range doc.ArgumentGroups {
+		for _, entry := range doc.Arguments[group] {
+			cmd.Parameters = append(cmd.Parameters, newParameter(group, entry))
+		}
+	}
+
+	c.Commands[path] = cmd
+	c.save()
+
+	return cmd, nil
+}
+
+func newParameter(group string, entry helpEntry) Parameter {
+	description, choices, defaultValue := splitArgumentDescription(entry.Description)
+	p := Parameter{
+		Options:     strings.Fields(entry.Name),
+		Group:       group,
+		Required:    hasHelpTag(entry.Tags, "Required"),
+		Choices:     choices,
+		Default:     defaultValue,
+		Description: description,
+		Status:      helpStatus(entry.Tags),
+	}
+
+	for _, option := range p.Options {
+		if strings.HasPrefix(option, "--") {
+			p.Name = option
+			break
+		}
+	}
+	if p.Name == "" && len(p.Options) > 0 {
+		p.Name = p.Options[0]
+	}
+
+	switch {
+	case len(choices) == 2 && choices[0] == "false" && choices[1] == "true":
+		p.Type = ParameterTypeBool
+	case len(choices) > 0:
+		p.Type = ParameterTypeEnum
+	case strings.Contains(strings.ToLower(description), "space-separated"),
+		strings.Contains(strings.ToLower(description), "space-delimited"):
+		p.Type = ParameterTypeList
+	default:
+		p.Type = ParameterTypeString
+	}
+
+	return p
+}
+
+func normalizeCommandPath(path string) string {
+	return strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(path), "az ")), " ")
+}
@@ -0,0 +1,159 @@
+package azcli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixtureCatalog returns a catalog answering help requests with the pages of
+// version in testdata/help.
+func fixtureCatalog(version string) *Catalog {
+	return NewCatalog(version, func(args ...string) (string, error) {
+		data, err := os.ReadFile(filepath.Join("testdata", "help", version, strings.Join(args, "_")+".txt"))
+		if err != nil {
+			return "", fmt.Errorf("no help page for %v in az %v", strings.Join(args, " "), version)
+		}
+		return string(data), nil
+	})
+}
+
+func TestCatalogGroup(t *testing.T) {
+	tests := []struct {
+		version  string
+		group    string
+		subgroup string
+		command  string
+		summary  string
+		// Status of commands or subgroups by name
+		status map[string]string
+	}{
+		{"2.0.81", "vm", "vm run-command", "stop", "Power off (stop) a running VM.", nil},
+		{"2.40.0", "vm", "vm host", "deallocate", "Deallocate a VM so that computing resources are no longer allocated (charges no longer apply). The status will change from 'Stopped' to 'Stopped (Deallocated)'.", map[string]string{"reimage": "Preview"}},
+		{"2.61.0", "aks", "aks oidc-issuer", "install-cli", "Download and install kubectl, the Kubernetes command-line tool. Download and install kubelogin, a client-go credential (exec) plugin implementing azure authentication.", map[string]string{"use-dev-spaces": "Deprecated", "oidc-issuer": "Preview"}},
+	}
+
+	for _, tt := range tests {
+		g, err := fixtureCatalog(tt.version).Group(tt.group)
+		if err != nil {
+			t.Errorf("az %v: %v", tt.version, err)
+			continue
+		}
+
+		subgroups := map[string]CommandGroup{}
+		for _, s := range g.Subgroups {
+			subgroups[s.Name] = s
+		}
+		if _, ok := subgroups[tt.subgroup]; !ok {
+			t.Errorf("az %v: no subgroup %q in %+v", tt.version, tt.subgroup, g.Subgroups)
+		}
+
+		commands := map[string]Command{}
+		for _, c := range g.Commands {
+			commands[c.Name] = c
+			if c.Group != tt.group {
+				t.Errorf("az %v: command %v in group %q", tt.version, c.Name, c.Group)
+			}
+		}
+		if c := commands[tt.command]; c.Summary != tt.summary {
+			t.Errorf("az %v: summary of %v %q, want %q", tt.version, tt.command, c.Summary, tt.summary)
+		}
+		for name, status := range tt.status {
+			if commands[name].Status != status && subgroups[tt.group+" "+name].Status != status {
+				t.Errorf("az %v: status of %v %q, want %q", tt.version, name, commands[name].Status, status)
+			}
+		}
+	}
+}
+
+func TestCatalogCommand(t *testing.T) {
+	tests := []struct {
+		version string
+		command string
+		params  []Parameter
+	}{
+		{"2.0.81", "vm stop", []Parameter{
+			{Name: "--skip-shutdown", Options: []string{"--skip-shutdown"}, Group: "Arguments", Type: ParameterTypeBool, Choices: []string{"false", "true"}, Description: "Skip shutdown and power-off immediately."},
+			{Name: "--ids", Options: []string{"--ids"}, Group: "Resource Id Arguments", Type: ParameterTypeList, Choices: []string{}, Description: "One or more resource IDs (space-delimited). It should be a complete resource ID containing all information of 'Resource Id' arguments. If provided, no other 'Resource Id' arguments should be specified."},
+			{Name: "--resource-group", Options: []string{"--resource-group", "-g"}, Group: "Resource Id Arguments", Type: ParameterTypeString, Choices: []string{}, Description: "Name of resource group. You can configure the default group using `az configure --defaults group=<name>`."},
+			{Name: "--output", Options: []string{"--output", "-o"}, Group: GlobalArgumentsGroup, Type: ParameterTypeEnum, Choices: []string{"json", "jsonc", "none", "table", "tsv", "yaml", "yamlc"}, Default: "json", Description: "Output format."},
+		}},
+		{"2.40.0", "vm stop", []Parameter{
+			{Name: "--skip-shutdown", Options: []string{"--skip-shutdown"}, Group: "Arguments", Type: ParameterTypeBool, Choices: []string{"false", "true"}, Description: "Skip shutdown and power-off immediately."},
+			{Name: "--name", Options: []string{"--name", "-n"}, Group: "Resource Id Arguments", Type: ParameterTypeString, Choices: []string{}, Description: "The name of the Virtual Machine. You can configure the default using `az configure --defaults vm=<name>`."},
+			{Name: "--only-show-errors", Options: []string{"--only-show-errors"}, Group: GlobalArgumentsGroup, Type: ParameterTypeString, Choices: []string{}, Description: "Only show errors, suppressing warnings."},
+		}},
+		{"2.61.0", "aks stop", []Parameter{
+			{Name: "--name", Options: []string{"--name", "-n"}, Group: "Arguments", Required: true, Type: ParameterTypeString, Choices: []string{}, Description: "Name of the managed cluster."},
+			{Name: "--resource-group", Options: []string{"--resource-group", "-g"}, Group: "Arguments", Required: true, Type: ParameterTypeString, Choices: []string{}, Description: "Name of resource group. You can configure the default group using `az configure --defaults group=<name>`."},
+		}},
+		{"2.61.0", "vm create", []Parameter{
+			{Name: "--admin-username", Options: []string{"--admin-username"}, Group: "Arguments", Type: ParameterTypeString, Choices: []string{}, Default: "root", Description: "Username for the VM. Default value is current username of OS. If the default value is system reserved, then default value will be set to azureuser."},
+			{Name: "--enable-hibernation", Options: []string{"--enable-hibernation"}, Group: "Arguments", Type: ParameterTypeBool, Choices: []string{"false", "true"}, Description: "The flag that enable or disable hibernation capability on the VM.", Status: "Preview"},
+			{Name: "--size", Options: []string{"--size"}, Group: "Arguments", Type: ParameterTypeString, Choices: []string{}, Default: "Standard_DS1_v2", Description: "The VM size to be created. See https://azure.microsoft.com/pricing/details/virtual-machines/ for size info."},
+			{Name: "--zone", Options: []string{"--zone", "-z"}, Group: "Arguments", Type: ParameterTypeEnum, Choices: []string{"1", "2", "3"}, Description: "Availability zone into which to provision the resource."},
+			{Name: "--public-ip-address-allocation", Options: []string{"--public-ip-address-allocation"}, Group: "Network Arguments", Type: ParameterTypeEnum, Choices: []string{"dynamic", "static"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		cmd, err := fixtureCatalog(tt.version).Command("az " + tt.command)
+		if err != nil {
+			t.Errorf("az %v: %v", tt.version, err)
+			continue
+		}
+		if cmd.Path() != tt.command {
+			t.Errorf("az %v: path %q, want %q", tt.version, cmd.Path(), tt.command)
+		}
+
+		for _, want := range tt.params {
+			got, ok := cmd.Parameter(want.Name)
+			if !ok {
+				t.Errorf("az %v %v: no parameter %v", tt.version, tt.command, want.Name)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("az %v %v: parameter\n%+v\nwant\n%+v", tt.version, tt.command, got, want)
+			}
+		}
+
+		for _, p := range cmd.CommandParameters() {
+			if p.Group == GlobalArgumentsGroup {
+				t.Errorf("az %v %v: global %v among the command parameters", tt.version, tt.command, p.Name)
+			}
+		}
+	}
+}
+
+func TestCatalogCachesPages(t *testing.T) {
+	asked := 0
+	c := NewCatalog("2.40.0", func(args ...string) (string, error) {
+		asked++
+		return fixtureCatalog("2.40.0").runHelp(args...)
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Group("vm"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Command("vm stop"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if asked != 2 {
+		t.Errorf("help asked %v times, want once per page", asked)
+	}
+}
+
+func TestCatalogUnknown(t *testing.T) {
+	c := fixtureCatalog("2.61.0")
+	if _, err := c.Command("vm"); err == nil {
+		t.Error("vm taken for a command")
+	}
+	if _, err := c.Group("no-such-group"); err == nil {
+		t.Error("unknown group found")
+	}
+}
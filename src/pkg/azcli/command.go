@@ -0,0 +1,105 @@
+package azcli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+// azCommand returns a command running the configured az executable with args
+// and the configured environment, including that of the active profile.
+func azCommand(args []string) *exec.Cmd {
+	settings := config.Current().AzCLI
+
+	path := settings.Path
+	if path == "" {
+		path = "az"
+	}
+
+	cmd := exec.Command(os.ExpandEnv(path), args...)
+	profileEnv := config.ActiveProfile().Env
+	if len(settings.Env) > 0 || len(profileEnv) > 0 {
+		cmd.Env = os.Environ()
+		// Later values win, so the profile's override azcli.env
+		for _, env := range []map[string]string{settings.Env, profileEnv} {
+			for name, value := range env {
+				cmd.Env = append(cmd.Env, name+"="+os.ExpandEnv(value))
+			}
+		}
+	}
+	setProcessGroup(cmd)
+
+	return cmd
+}
+
+// withTimeout limits ctx to the configured command timeout, if any.
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := config.Current().AzCLI.Timeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// killOnDone kills the process group of the started cmd once ctx ends,
+// unless the returned func was called first. az is a wrapper script around
+// python, so the whole group has to go for the output pipes to be closed.
+// The returned func must be called as soon as cmd.Wait returns: from then on
+// the group is never signaled, since its id may be reused. If ctx ends in the
+// short window between Wait reaping az and that call, the group is still
+// signaled, which only reaches another process if the id was reused in
+// between.
+func killOnDone(ctx context.Context, cmd *exec.Cmd) func() {
+	var mu sync.Mutex
+	waited := false
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			defer mu.Unlock()
+			if !waited {
+				killProcessGroup(cmd)
+			}
+		case <-done:
+		}
+	}()
+	return func() {
+		mu.Lock()
+		waited = true
+		mu.Unlock()
+		close(done)
+	}
+}
+
+// runAz runs az with args and waits for it to exit, killing it once ctx ends
+// or the configured timeout passes.
+func runAz(ctx context.Context, args []string) (string, string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	cmd := azCommand(args)
+
+	// Create buffers to capture stdout and stderr
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	if err := cmd.Start(); err != nil {
+		return "", "", fmt.Errorf("failed to start az: %v", err)
+	}
+
+	exited := killOnDone(ctx, cmd)
+	err := cmd.Wait()
+	exited()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("az timed out after %v", config.Current().AzCLI.Timeout)
+	}
+
+	return stdoutBuf.String(), stderrBuf.String(), err
+}
@@ -0,0 +1,166 @@
+package azcli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/dryrun"
+)
+
+type Stream int
+
+const (
+	Stdout Stream = iota
+	Stderr
+)
+
+type Result struct {
+	Args     []string
+	ExitCode int
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
+	Canceled bool
+	Err      error
+}
+
+// Execution is an az command running in the background. Output is delivered
+// line by line to the callback passed to StartAzCommand while the command
+// runs, and the complete output is available in the Result once it exits.
+type Execution struct {
+	Args      []string
+	StartTime time.Time
+	cmd       *exec.Cmd
+	cancel    context.CancelFunc
+	done      chan struct{}
+	result    Result
+}
+
+// StartAzCommand starts az with args without waiting for it to exit. onLine
+// is called from a background goroutine for every line az writes to stdout or
+// stderr, and onExit once the command has exited and all output has been
+// delivered. Either callback may be nil. The configured default arguments
+// are added to args and the command is killed after the configured timeout.
+//
+// Mutating commands are refused in strict dry-run mode. Confirming them in
+// preview mode is left to the caller, which can't block here.
+func StartAzCommand(ctx context.Context, args []string, onLine func(stream Stream, line string), onExit func(Result)) (*Execution, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty args list")
+	}
+
+	if dryrun.CurrentMode() == dryrun.Strict && !IsReadOnlyCommand(args) {
+		return nil, fmt.Errorf("%w: %v", dryrun.ErrDryRun, ShellCommand(args))
+	}
+
+	args = WithDefaultArgs(args)
+	ctx, cancel := withTimeout(ctx)
+	cmd := azCommand(args)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	e := &Execution{
+		Args:      args,
+		StartTime: time.Now(),
+		cmd:       cmd,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start az: %v", err)
+	}
+
+	exited := killOnDone(ctx, cmd)
+
+	var mu sync.Mutex
+	var stdoutBuf, stderrBuf strings.Builder
+	var wg sync.WaitGroup
+	scan := func(r io.Reader, stream Stream, buf *strings.Builder) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			mu.Lock()
+			buf.WriteString(line)
+			buf.WriteString("\n")
+			if onLine != nil {
+				onLine(stream, line)
+			}
+			mu.Unlock()
+		}
+	}
+
+	wg.Add(2)
+	go scan(stdout, Stdout, &stdoutBuf)
+	go scan(stderr, Stderr, &stderrBuf)
+
+	go func() {
+		wg.Wait()
+		err := cmd.Wait()
+		exited()
+
+		e.result = Result{
+			Args:     args,
+			ExitCode: cmd.ProcessState.ExitCode(),
+			Duration: time.Since(e.StartTime),
+			Stdout:   stdoutBuf.String(),
+			Stderr:   stderrBuf.String(),
+			Canceled: ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded),
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			e.result.Err = fmt.Errorf("az timed out after %v", config.Current().AzCLI.Timeout)
+		} else if err != nil {
+			e.result.Err = fmt.Errorf("%v: %v", err, strings.TrimSpace(e.result.Stderr))
+		}
+
+		cancel()
+		close(e.done)
+
+		if onExit != nil {
+			onExit(e.result)
+		}
+	}()
+
+	return e, nil
+}
+
+// Cancel kills the command. It is safe to call after the command exited.
+func (e *Execution) Cancel() {
+	e.cancel()
+}
+
+// Wait blocks until the command exits and returns its result.
+func (e *Execution) Wait() Result {
+	<-e.done
+	return e.result
+}
+
+func (e *Execution) Running() bool {
+	select {
+	case <-e.done:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package azcli
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+
+	// A negative pid signals every process in the group
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,198 @@
+package azcli
+
+import (
+	"strings"
+)
+
+// az does not offer a machine readable form of its help, so the catalog is
+// built from the sections knack prints for `az <group> --help`. Every
+// section starts with an unindented title, entries are indented by exactly
+// four spaces and wrapped descriptions are indented further. Parsing on that
+// layout instead of on ":" keeps subgroups, multi-colon descriptions and
+// status tags intact. --output doesn't apply to --help and az doesn't
+// translate its help, so only the az version changes the text, the tests
+// parse the pages of several versions in testdata/help.
+
+const helpEntryIndent = "    "
+
+type helpSection struct {
+	Title string
+	Lines []string
+}
+
+type helpEntry struct {
+	Name        string
+	Tags        []string
+	Description string
+}
+
+type helpDoc struct {
+	Summary   string
+	Status    string
+	Subgroups []helpEntry
+	Commands  []helpEntry
+	Arguments map[string][]helpEntry
+	// Order in which argument sections appeared in the help text
+	ArgumentGroups []string
+}
+
+func splitHelpSections(text string) []helpSection {
+	sections := []helpSection{}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") {
+			title := strings.TrimSuffix(strings.TrimSpace(line), ":")
+			sections = append(sections, helpSection{Title: title})
+			continue
+		}
+
+		if len(sections) == 0 {
+			continue
+		}
+
+		sections[len(sections)-1].Lines = append(sections[len(sections)-1].Lines, line)
+	}
+
+	return sections
+}
+
+func isHelpEntryLine(line string) bool {
+	return strings.HasPrefix(line, helpEntryIndent) && len(line) > len(helpEntryIndent) && line[len(helpEntryIndent)] != ' '
+}
+
+// Split the name column of an entry into the name itself and the bracketed
+// tags knack appends to it, e.g. "host [Preview]" or "--name -n [Required]".
+func splitHelpTags(column string) (string, []string) {
+	tags := []string{}
+	name := column
+	for {
+		name = strings.TrimSpace(name)
+		if !strings.HasSuffix(name, "]") {
+			break
+		}
+
+		start := strings.LastIndex(name, "[")
+		if start == -1 {
+			break
+		}
+
+		tags = append([]string{name[start+1 : len(name)-1]}, tags...)
+		name = name[:start]
+	}
+
+	return name, tags
+}
+
+func parseHelpEntries(lines []string) []helpEntry {
+	entries := []helpEntry{}
+	for _, line := range lines {
+		if !isHelpEntryLine(line) {
+			if len(entries) > 0 {
+				last := &entries[len(entries)-1]
+				// textwrap breaks long words such as URLs on hyphens
+				separator := " "
+				if strings.HasSuffix(last.Description, "-") && !strings.HasSuffix(last.Description, " -") {
+					separator = ""
+				}
+				last.Description = strings.TrimSpace(last.Description + separator + strings.TrimSpace(line))
+			}
+			continue
+		}
+
+		column, description := strings.TrimSpace(line), ""
+		if idx := strings.Index(line, " : "); idx != -1 {
+			column = strings.TrimSpace(line[:idx])
+			description = strings.TrimSpace(line[idx+3:])
+		}
+
+		name, tags := splitHelpTags(column)
+		entries = append(entries, helpEntry{
+			Name:        name,
+			Tags:        tags,
+			Description: description,
+		})
+	}
+
+	return entries
+}
+
+func parseHelpDoc(text string) helpDoc {
+	doc := helpDoc{
+		Arguments: make(map[string][]helpEntry),
+	}
+
+	for _, section := range splitHelpSections(text) {
+		switch {
+		case section.Title == "Group" || section.Title == "Command":
+			entries := parseHelpEntries(section.Lines)
+			if len(entries) > 0 {
+				doc.Summary = entries[0].Description
+				doc.Status = helpStatus(entries[0].Tags)
+			}
+		case section.Title == "Subgroups":
+			doc.Subgroups = append(doc.Subgroups, parseHelpEntries(section.Lines)...)
+		case section.Title == "Commands":
+			doc.Commands = append(doc.Commands, parseHelpEntries(section.Lines)...)
+		case strings.HasSuffix(section.Title, "Arguments"):
+			if _, ok := doc.Arguments[section.Title]; !ok {
+				doc.ArgumentGroups = append(doc.ArgumentGroups, section.Title)
+			}
+			doc.Arguments[section.Title] = append(doc.Arguments[section.Title], parseHelpEntries(section.Lines)...)
+		}
+	}
+
+	return doc
+}
+
+// The status of a group, command or argument is the first tag that is not
+// "Required", e.g. Preview, Experimental or Deprecated.
+func helpStatus(tags []string) string {
+	for _, tag := range tags {
+		if tag != "Required" {
+			return tag
+		}
+	}
+
+	return ""
+}
+
+func hasHelpTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// knack appends "Allowed values: a, b." and "Default: x." to the argument
+// description before wrapping it, so both are extracted from the joined text.
+func splitArgumentDescription(description string) (string, []string, string) {
+	const allowedMarker = "Allowed values: "
+	const defaultMarker = "Default: "
+
+	text := description
+	choices := []string{}
+	defaultValue := ""
+
+	if idx := strings.Index(text, defaultMarker); idx != -1 {
+		defaultValue = strings.TrimSuffix(strings.TrimSpace(text[idx+len(defaultMarker):]), ".")
+		text = text[:idx]
+	}
+
+	if idx := strings.Index(text, allowedMarker); idx != -1 {
+		values := strings.TrimSuffix(strings.TrimSpace(text[idx+len(allowedMarker):]), ".")
+		for _, value := range strings.Split(values, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				choices = append(choices, value)
+			}
+		}
+		text = text[:idx]
+	}
+
+	return strings.TrimSpace(text), choices, defaultValue
+}
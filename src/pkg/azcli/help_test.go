@@ -0,0 +1,88 @@
+package azcli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitHelpTags(t *testing.T) {
+	tests := []struct {
+		column string
+		name   string
+		tags   []string
+	}{
+		{"stop", "stop", []string{}},
+		{"host [Preview]", "host", []string{"Preview"}},
+		{"--name -n           [Required]", "--name -n", []string{"Required"}},
+		{"--enable-x [Preview] [Required]", "--enable-x", []string{"Preview", "Required"}},
+		{"--tags [key=value]x", "--tags [key=value]x", []string{}},
+	}
+
+	for _, tt := range tests {
+		name, tags := splitHelpTags(tt.column)
+		if name != tt.name || !reflect.DeepEqual(tags, tt.tags) {
+			t.Errorf("splitHelpTags(%q) = %q, %q, want %q, %q", tt.column, name, tags, tt.name, tt.tags)
+		}
+	}
+}
+
+func TestSplitArgumentDescription(t *testing.T) {
+	tests := []struct {
+		description  string
+		text         string
+		choices      []string
+		defaultValue string
+	}{
+		{"Name of the virtual machine.", "Name of the virtual machine.", []string{}, ""},
+		{"Skip shutdown.  Allowed values: false, true.", "Skip shutdown.", []string{"false", "true"}, ""},
+		{"Output format.  Allowed values: json, table, tsv.  Default: json.", "Output format.", []string{"json", "table", "tsv"}, "json"},
+		{"The VM size.  Default: Standard_DS1_v2.", "The VM size.", []string{}, "Standard_DS1_v2"},
+		{"Allowed values: dynamic, static.", "", []string{"dynamic", "static"}, ""},
+	}
+
+	for _, tt := range tests {
+		text, choices, defaultValue := splitArgumentDescription(tt.description)
+		if text != tt.text || !reflect.DeepEqual(choices, tt.choices) || defaultValue != tt.defaultValue {
+			t.Errorf("splitArgumentDescription(%q) = %q, %q, %q, want %q, %q, %q", tt.description, text, choices, defaultValue, tt.text, tt.choices, tt.defaultValue)
+		}
+	}
+}
+
+func TestParseHelpEntries(t *testing.T) {
+	lines := []string{
+		"    --ids               : One or more resource IDs (space-delimited). It should be a complete",
+		"                          resource ID.",
+		"    --size              : See https://azure.microsoft.com/pricing/details/virtual-",
+		"                          machines/ for size info.",
+		"    --name -n [Required] : Name: of the VM.",
+		"    --no-wait",
+	}
+	want := []helpEntry{
+		{Name: "--ids", Tags: []string{}, Description: "One or more resource IDs (space-delimited). It should be a complete resource ID."},
+		{Name: "--size", Tags: []string{}, Description: "See https://azure.microsoft.com/pricing/details/virtual-machines/ for size info."},
+		{Name: "--name -n", Tags: []string{"Required"}, Description: "Name: of the VM."},
+		{Name: "--no-wait", Tags: []string{}, Description: ""},
+	}
+
+	if got := parseHelpEntries(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseHelpEntries() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseHelpDocSections(t *testing.T) {
+	text := "\r\nGroup\r\n    az vm : Manage VMs.\r\n\r\nSubgroups:\r\n    disk : Manage disks.\r\n\r\nCommands:\r\n    stop [Preview] : Stop a VM.\r\n\r\nExamples\r\n    az vm stop -n vm\r\n"
+	doc := parseHelpDoc(text)
+
+	if doc.Summary != "Manage VMs." {
+		t.Errorf("summary %q", doc.Summary)
+	}
+	if len(doc.Subgroups) != 1 || doc.Subgroups[0].Name != "disk" {
+		t.Errorf("subgroups %+v", doc.Subgroups)
+	}
+	if len(doc.Commands) != 1 || doc.Commands[0].Name != "stop" || helpStatus(doc.Commands[0].Tags) != "Preview" {
+		t.Errorf("commands %+v", doc.Commands)
+	}
+	if len(doc.ArgumentGroups) != 0 {
+		t.Errorf("argument groups %v from examples", doc.ArgumentGroups)
+	}
+}
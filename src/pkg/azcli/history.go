@@ -0,0 +1,324 @@
+package azcli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+)
+
+// Output beyond this size is not kept in the history, the head of the output
+// is usually what identifies a run.
+const maxHistoryOutput = 64 * 1024
+
+// HistoryEntry is one executed az command.
+type HistoryEntry struct {
+	Args      []string      `json:"args"`
+	Target    string        `json:"target,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+	ExitCode  int           `json:"exitCode"`
+	Duration  time.Duration `json:"duration"`
+	Canceled  bool          `json:"canceled,omitempty"`
+	Output    string        `json:"output,omitempty"`
+}
+
+var (
+	historyMu sync.Mutex
+	// Lines in the history file, -1 until it is first read, see trimHistory
+	historyLines = -1
+	// Told about every entry recorded, see SetHistoryFunc
+	historyFunc func(HistoryEntry)
+)
+
+// SetHistoryFunc sets the function told about every command recorded in the
+// history, so the history can be shown without reading the file again. It is
+// called from the goroutine recording the command.
+func SetHistoryFunc(f func(HistoryEntry)) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	historyFunc = f
+}
+
+// MaxHistoryEntries returns how many commands the history keeps, 0 for no
+// limit.
+func MaxHistoryEntries() int {
+	return max(config.Current().History.MaxEntries, 0)
+}
+
+// HistoryPath returns the file executed commands are recorded in, under
+// $XDG_DATA_HOME/aztui, or ~/.local/share/aztui when it is unset.
+func HistoryPath() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataDir, "aztui", "history.jsonl"), nil
+}
+
+// NewHistoryEntry returns the entry recording result, with its output unless
+// the configuration leaves output out of the history.
+func NewHistoryEntry(result Result, target string) HistoryEntry {
+	output := ""
+	if config.Current().History.Output {
+		output = result.Stdout + result.Stderr
+	}
+	if len(output) > maxHistoryOutput {
+		// Cut before the rune crossing the limit, keeping the output valid
+		// UTF-8
+		end := maxHistoryOutput
+		for end > 0 && !utf8.RuneStart(output[end]) {
+			end--
+		}
+		output = output[:end] + "\n... (truncated)\n"
+	}
+
+	return HistoryEntry{
+		Args:      result.Args,
+		Target:    target,
+		Timestamp: time.Now().Add(-result.Duration),
+		ExitCode:  result.ExitCode,
+		Duration:  result.Duration,
+		Canceled:  result.Canceled,
+		Output:    output,
+	}
+}
+
+// AppendHistory records entry at the end of the history file.
+func AppendHistory(entry HistoryEntry) error {
+	path, err := HistoryPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := appendHistoryLine(path, data); err != nil {
+		return err
+	}
+
+	historyMu.Lock()
+	f := historyFunc
+	historyMu.Unlock()
+	if f != nil {
+		f(entry)
+	}
+
+	return nil
+}
+
+// appendHistoryLine appends line to the history file at path, then trims
+// the file. Failing to trim it only costs disk space, so it is logged.
+func appendHistoryLine(path string, line []byte) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// Command output can contain secrets, keep the history private
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := trimHistory(path); err != nil {
+		logger.Warn("Failed to trim command history", "err", err)
+	}
+	return nil
+}
+
+// trimHistory drops the oldest commands from the history file at path once
+// it holds a tenth more than the configured maximum, so the file isn't
+// rewritten for every command. Called with historyMu held after a line was
+// appended.
+func trimHistory(path string) error {
+	limit := MaxHistoryEntries()
+	if historyLines >= 0 {
+		historyLines++
+	}
+	if limit == 0 || historyLines >= 0 && historyLines <= limit+limit/10 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := bytes.SplitAfter(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	historyLines = len(lines)
+	if historyLines <= limit+limit/10 {
+		return nil
+	}
+
+	kept := bytes.Join(lines[len(lines)-limit:], nil)
+	if !bytes.HasSuffix(kept, []byte("\n")) {
+		kept = append(kept, '\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	historyLines = limit
+
+	return nil
+}
+
+// LoadHistory returns the recorded commands, oldest first. Lines that can't
+// be decoded are skipped so a truncated write doesn't lose the whole history.
+func LoadHistory() ([]HistoryEntry, error) {
+	path, err := HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []HistoryEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*maxHistoryOutput)
+	for scanner.Scan() {
+		entry := HistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if limit := MaxHistoryEntries(); limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	return entries, scanner.Err()
+}
+
+// ShellCommand returns the entry as a command line that can be pasted into a
+// POSIX shell.
+func (e HistoryEntry) ShellCommand() string {
+	return ShellCommand(e.Args)
+}
+
+func ShellCommand(args []string) string {
+	quoted := []string{"az"}
+	for _, arg := range args {
+		quoted = append(quoted, ShellQuote(arg))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func ShellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+
+	safe := true
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_@%+=:,./-", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// SplitShellCommand splits a command line the way a POSIX shell would,
+// honouring single quotes, double quotes and backslash escapes. A leading
+// "az" is dropped.
+func SplitShellCommand(line string) ([]string, error) {
+	args := []string{}
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	if len(args) > 0 && args[0] == "az" {
+		args = args[1:]
+	}
+
+	return args, nil
+}
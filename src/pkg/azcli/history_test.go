@@ -0,0 +1,187 @@
+package azcli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"", "''"},
+		{"vm", "vm"},
+		{"--resource-group", "--resource-group"},
+		{"user@example.com", "user@example.com"},
+		{"a=b,c:d/e.f+g%", "a=b,c:d/e.f+g%"},
+		{"two words", "'two words'"},
+		{"it's", `'it'"'"'s'`},
+		{"$HOME", "'$HOME'"},
+		{"[?name=='x']", `'[?name=='"'"'x'"'"']'`},
+	}
+
+	for _, tt := range tests {
+		if got := ShellQuote(tt.arg); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestSplitShellCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		err  bool
+	}{
+		{"az vm list", []string{"vm", "list"}, false},
+		{"vm list", []string{"vm", "list"}, false},
+		{"  az\tvm   show\n-n vm ", []string{"vm", "show", "-n", "vm"}, false},
+		{`az vm list --query '[?name==''x'']'`, []string{"vm", "list", "--query", "[?name==x]"}, false},
+		{`az tag create --name "a b" --value 'c "d"'`, []string{"tag", "create", "--name", "a b", "--value", `c "d"`}, false},
+		{`az x --a "q\"uote" --b back\ slash`, []string{"x", "--a", `q"uote`, "--b", "back slash"}, false},
+		{`az x --empty '' --also ""`, []string{"x", "--empty", "", "--also", ""}, false},
+		{"", []string{}, false},
+		{"az x 'open", nil, true},
+		{`az x "open`, nil, true},
+		{`az x trailing\`, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitShellCommand(tt.line)
+		if (err != nil) != tt.err {
+			t.Errorf("SplitShellCommand(%q) error %v, want error %v", tt.line, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitShellCommand(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestShellCommandRoundTrip(t *testing.T) {
+	args := [][]string{
+		{"vm", "list"},
+		{"vm", "run-command", "invoke", "--scripts", "echo 'hi' && ls $HOME"},
+		{"tag", "create", "--name", "", "--value", `back\slash "quoted"`},
+	}
+
+	for _, a := range args {
+		got, err := SplitShellCommand(ShellCommand(a))
+		if err != nil || !reflect.DeepEqual(got, a) {
+			t.Errorf("SplitShellCommand(ShellCommand(%q)) = %q, %v", a, got, err)
+		}
+	}
+}
+
+// useHistoryConfig records the history in a temporary directory with the
+// history settings of yaml.
+func useHistoryConfig(t *testing.T, yaml string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	path := filepath.Join(dir, "aztui.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	historyMu.Lock()
+	historyLines = -1
+	historyMu.Unlock()
+	t.Cleanup(func() {
+		config.LoadConfig("")
+		SetHistoryFunc(nil)
+	})
+
+	return filepath.Join(dir, "aztui", "history.jsonl")
+}
+
+func TestHistoryTrimmed(t *testing.T) {
+	path := useHistoryConfig(t, "history:\n  maxEntries: 10\n")
+
+	recorded := 0
+	SetHistoryFunc(func(HistoryEntry) { recorded++ })
+	for i := 0; i < 25; i++ {
+		if err := AppendHistory(HistoryEntry{Args: []string{"group", "show", "-n", fmt.Sprint(i)}}); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := 0
+		for _, b := range data {
+			if b == '\n' {
+				lines++
+			}
+		}
+		if lines > 11 {
+			t.Fatalf("%v lines in the history after %v commands, want at most 11", lines, i+1)
+		}
+	}
+	if recorded != 25 {
+		t.Errorf("history func told about %v commands, want 25", recorded)
+	}
+
+	entries, err := LoadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 10 || entries[0].Args[3] != "15" || entries[9].Args[3] != "24" {
+		t.Errorf("history kept %v", entries)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("history file mode %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestHistoryOutput(t *testing.T) {
+	result := Result{Args: []string{"keyvault", "secret", "show"}, Stdout: "secret\n", Stderr: "warning\n"}
+
+	useHistoryConfig(t, "history:\n  output: true\n")
+	if entry := NewHistoryEntry(result, ""); entry.Output != "secret\nwarning\n" {
+		t.Errorf("output %q recorded", entry.Output)
+	}
+
+	useHistoryConfig(t, "history:\n  output: false\n")
+	if entry := NewHistoryEntry(result, ""); entry.Output != "" {
+		t.Errorf("output %q recorded with output: false", entry.Output)
+	}
+}
+
+func TestHistoryOutputTruncatedOnRuneBoundary(t *testing.T) {
+	useHistoryConfig(t, "history:\n  output: true\n")
+
+	// Every offset of the limit into a multi-byte rune
+	for pad := 0; pad < 4; pad++ {
+		stdout := strings.Repeat("a", maxHistoryOutput-pad) + strings.Repeat("€𝄞", 10)
+		entry := NewHistoryEntry(Result{Args: []string{"vm", "list"}, Stdout: stdout}, "")
+
+		if !utf8.ValidString(entry.Output) {
+			t.Errorf("invalid UTF-8 recorded cutting %v bytes before the limit", pad)
+		}
+		if !strings.HasSuffix(entry.Output, "\n... (truncated)\n") {
+			t.Errorf("output not marked as truncated")
+		}
+		if kept := len(strings.TrimSuffix(entry.Output, "\n... (truncated)\n")); kept > maxHistoryOutput || kept < maxHistoryOutput-3 {
+			t.Errorf("%v bytes of output kept, limit %v", kept, maxHistoryOutput)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package azcli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const ResourceGroupType = "Microsoft.Resources/resourceGroups"
+
+// az command groups that manage each ARM resource type, keyed by the lower
+// case resource type.
+var resourceTypeCommandGroups = map[string][]string{
+	"microsoft.app/containerapps":                      {"containerapp"},
+	"microsoft.cache/redis":                            {"redis"},
+	"microsoft.compute/availabilitysets":               {"vm availability-set"},
+	"microsoft.compute/disks":                          {"disk"},
+	"microsoft.compute/images":                         {"image"},
+	"microsoft.compute/snapshots":                      {"snapshot"},
+	"microsoft.compute/virtualmachines":                {"vm"},
+	"microsoft.compute/virtualmachinescalesets":        {"vmss"},
+	"microsoft.containerinstance/containergroups":      {"container"},
+	"microsoft.containerregistry/registries":           {"acr"},
+	"microsoft.containerservice/managedclusters":       {"aks"},
+	"microsoft.dbformysql/flexibleservers":             {"mysql flexible-server"},
+	"microsoft.dbforpostgresql/flexibleservers":        {"postgres flexible-server"},
+	"microsoft.documentdb/databaseaccounts":            {"cosmosdb"},
+	"microsoft.eventhub/namespaces":                    {"eventhubs namespace"},
+	"microsoft.keyvault/vaults":                        {"keyvault"},
+	"microsoft.managedidentity/userassignedidentities": {"identity"},
+	"microsoft.network/applicationgateways":            {"network application-gateway"},
+	"microsoft.network/dnszones":                       {"network dns zone"},
+	"microsoft.network/loadbalancers":                  {"network lb"},
+	"microsoft.network/networkinterfaces":              {"network nic"},
+	"microsoft.network/networksecuritygroups":          {"network nsg"},
+	"microsoft.network/privatednszones":                {"network private-dns zone"},
+	"microsoft.network/privateendpoints":               {"network private-endpoint"},
+	"microsoft.network/publicipaddresses":              {"network public-ip"},
+	"microsoft.network/routetables":                    {"network route-table"},
+	"microsoft.network/virtualnetworks":                {"network vnet"},
+	"microsoft.operationalinsights/workspaces":         {"monitor log-analytics workspace"},
+	"microsoft.resources/resourcegroups":               {"group"},
+	"microsoft.servicebus/namespaces":                  {"servicebus namespace"},
+	"microsoft.sql/servers":                            {"sql server"},
+	"microsoft.sql/servers/databases":                  {"sql db"},
+	"microsoft.storage/storageaccounts":                {"storage account"},
+	"microsoft.web/serverfarms":                        {"appservice plan"},
+	"microsoft.web/sites":                              {"webapp", "functionapp"},
+}
+
+// az command groups added by extensions, keyed by the extension name and
+// then like resourceTypeCommandGroups. Extensions that only add commands to
+// existing groups, like aks-preview, don't need an entry.
+var extensionCommandGroups = map[string]map[string][]string{
+	"application-insights": {
+		"microsoft.insights/components": {"monitor app-insights component"},
+	},
+	"bastion": {
+		"microsoft.network/bastionhosts": {"network bastion"},
+	},
+	"front-door": {
+		"microsoft.network/frontdoors": {"network front-door"},
+	},
+	"serial-console": {
+		"microsoft.compute/virtualmachines": {"serial-console"},
+	},
+	"ssh": {
+		"microsoft.compute/virtualmachines": {"ssh"},
+	},
+	"virtual-wan": {
+		"microsoft.network/virtualhubs": {"network vhub"},
+		"microsoft.network/virtualwans": {"network vwan"},
+	},
+}
+
+// Target is the resource an az command is run against.
+type Target struct {
+	SubscriptionID string
+	ResourceGroup  string
+	// Resource type, e.g. Microsoft.Compute/virtualMachines
+	Type string
+	// Resource name, "parent/child" for nested resource types
+	Name string
+}
+
+// CommandGroupsForResourceType returns the az command groups that manage a
+// resource type, e.g. "storage account" for Microsoft.Storage/storageAccounts.
+// Groups added by installed extensions come after the built-in ones.
+func CommandGroupsForResourceType(resourceType string) []string {
+	resourceType = strings.ToLower(resourceType)
+	groups := append([]string{}, resourceTypeCommandGroups[resourceType]...)
+
+	catalog, err := GetCatalog()
+	if err != nil {
+		return groups
+	}
+
+	extensions := make([]string, 0, len(catalog.Extensions))
+	for name := range catalog.Extensions {
+		extensions = append(extensions, name)
+	}
+	sort.Strings(extensions)
+
+	for _, name := range extensions {
+		groups = append(groups, extensionCommandGroups[name][resourceType]...)
+	}
+
+	return groups
+}
+
+// ID returns the ARM resource ID of the target.
+func (t Target) ID() string {
+	if strings.EqualFold(t.Type, ResourceGroupType) {
+		return fmt.Sprintf("/subscriptions/%v/resourceGroups/%v", t.SubscriptionID, t.Name)
+	}
+
+	return ResourceID(t.SubscriptionID, t.ResourceGroup, t.Type, t.Name)
+}
+
+// ResourceID builds the ARM ID of a resource from its type and name,
+// interleaving the segments of nested types such as
+// Microsoft.Sql/servers/databases with those of names such as "server/db".
+func ResourceID(subscriptionID, resourceGroup, resourceType, name string) string {
+	typeSegments := strings.Split(resourceType, "/")
+	nameSegments := strings.Split(name, "/")
+
+	id := fmt.Sprintf("/subscriptions/%v/resourceGroups/%v/providers/%v", subscriptionID, resourceGroup, typeSegments[0])
+	for i, t := range typeSegments[1:] {
+		id += "/" + t
+		if i < len(nameSegments) {
+			id += "/" + nameSegments[i]
+		}
+	}
+
+	return id
+}
+
+// KnownValues returns the parameter values of command that can be derived
+// from the target. Commands of group, the group managing the target's type,
+// get the target by --ids when they accept it and by resource group and name
+// otherwise. Commands of subgroups manage child resources, so only the
+// resource group and the name of the parent are filled in.
+func (t Target) KnownValues(command Command, group string) map[string]string {
+	known := map[string]string{
+		"--subscription": t.SubscriptionID,
+	}
+
+	if command.Group != group {
+		if t.ResourceGroup != "" {
+			known["--resource-group"] = t.ResourceGroup
+		}
+		fields := strings.Fields(group)
+		if len(fields) > 0 {
+			known["--"+fields[len(fields)-1]+"-name"] = t.Name
+		}
+		return known
+	}
+
+	if command.HasParameter("--ids") {
+		known["--ids"] = t.ID()
+		// --ids already names the subscription
+		delete(known, "--subscription")
+		return known
+	}
+
+	if t.ResourceGroup != "" {
+		known["--resource-group"] = t.ResourceGroup
+	}
+	known["--name"] = t.Name
+
+	return known
+}
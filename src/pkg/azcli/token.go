@@ -0,0 +1,61 @@
+package azcli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccessToken is a token of the az login, see GetAccessToken.
+type AccessToken struct {
+	Token     string
+	ExpiresOn time.Time
+}
+
+// GetAccessToken returns a token of the az login for resource, e.g.
+// https://management.azure.com, in tenant or the login's default tenant when
+// empty. az runs with the same environment as every other command, so the
+// token is that of the active profile's login.
+func GetAccessToken(ctx context.Context, resource, tenant string) (AccessToken, error) {
+	args := []string{"account", "get-access-token", "--output", "json", "--resource", resource}
+	if tenant != "" {
+		args = append(args, "--tenant", tenant)
+	}
+
+	stdout, stderr, err := runAz(ctx, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			return AccessToken{}, ctx.Err()
+		}
+		return AccessToken{}, fmt.Errorf("az account get-access-token failed: %v: %v", err, strings.TrimSpace(stderr))
+	}
+
+	return parseAccessToken(stdout)
+}
+
+func parseAccessToken(output string) (AccessToken, error) {
+	token := struct {
+		AccessToken string `json:"accessToken"`
+		// Unix time, added in az 2.54
+		ExpiresOnUnix int64 `json:"expires_on"`
+		// Local time, e.g. "2024-01-02 15:04:05.000000"
+		ExpiresOn string `json:"expiresOn"`
+	}{}
+	if err := json.Unmarshal([]byte(output), &token); err != nil {
+		return AccessToken{}, fmt.Errorf("failed to parse az account get-access-token output: %v", err)
+	}
+	if token.AccessToken == "" {
+		return AccessToken{}, fmt.Errorf("az account get-access-token returned no token")
+	}
+
+	if token.ExpiresOnUnix != 0 {
+		return AccessToken{token.AccessToken, time.Unix(token.ExpiresOnUnix, 0)}, nil
+	}
+	expiresOn, err := time.ParseInLocation("2006-01-02 15:04:05.999999", token.ExpiresOn, time.Local)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("failed to parse the expiry of the az token: %v", err)
+	}
+	return AccessToken{token.AccessToken, expiresOn}, nil
+}
@@ -0,0 +1,44 @@
+package azcli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAccessToken(t *testing.T) {
+	tests := []struct {
+		output    string
+		token     string
+		expiresOn time.Time
+	}{
+		{
+			`{"accessToken": "t", "expiresOn": "2030-01-02 03:04:05.123456", "expires_on": 1893553445, "tokenType": "Bearer"}`,
+			"t", time.Unix(1893553445, 0),
+		},
+		{
+			// Before az 2.54
+			`{"accessToken": "t", "expiresOn": "2030-01-02 03:04:05.123456", "tokenType": "Bearer"}`,
+			"t", time.Date(2030, 1, 2, 3, 4, 5, 123456000, time.Local),
+		},
+		{`{"accessToken": "t", "expiresOn": "soon"}`, "", time.Time{}},
+		{`{"expires_on": 1893553445}`, "", time.Time{}},
+		{`ERROR: Please run 'az login'`, "", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		token, err := parseAccessToken(tt.output)
+		if tt.token == "" {
+			if err == nil {
+				t.Errorf("parseAccessToken(%q) = %v, want an error", tt.output, token)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAccessToken(%q): %v", tt.output, err)
+			continue
+		}
+		if token.Token != tt.token || !token.ExpiresOn.Equal(tt.expiresOn) {
+			t.Errorf("parseAccessToken(%q) = %v, want %v %v", tt.output, token, tt.token, tt.expiresOn)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package azclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/brendank310/aztui/pkg/dryrun"
+)
+
+// ClientOptions returns the options every ARM client is created with, for
+// the cloud of the active profile and with the configured retries.
+func ClientOptions() *arm.ClientOptions {
+	return &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Cloud:            Cloud(),
+			PerCallPolicies:  []policy.Policy{tracePolicy{}, dryRunPolicy{}},
+			PerRetryPolicies: []policy.Policy{throttlePolicy{}},
+			Retry:            retryOptions(),
+		},
+	}
+}
+
+type readOnlyKey struct{}
+
+// ReadOnly marks requests made with ctx as not changing resources despite
+// their method, e.g. the POST that connects to a serial console, so dry-run
+// doesn't hold them back.
+func ReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// dryRunPolicy holds back requests that change resources according to the
+// dry-run mode.
+type dryRunPolicy struct{}
+
+func (dryRunPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	switch raw.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Next()
+	}
+
+	if readOnly, _ := raw.Context().Value(readOnlyKey{}).(bool); readOnly || dryrun.CurrentMode() == dryrun.Off {
+		return req.Next()
+	}
+
+	preview := dryrun.Request{
+		Method: raw.Method,
+		URL:    raw.URL.String(),
+	}
+	if body := req.Body(); body != nil {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.RewindBody(); err != nil {
+			return nil, err
+		}
+		preview.Body = string(data)
+	}
+
+	if err := dryrun.Check(preview); err != nil {
+		return nil, err
+	}
+
+	return req.Next()
+}
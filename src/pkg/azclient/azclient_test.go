@@ -0,0 +1,111 @@
+package azclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+
+	"github.com/brendank310/aztui/pkg/dryrun"
+)
+
+// recordingTransport answers every request with 200 and counts them.
+type recordingTransport struct {
+	sent int
+}
+
+func (t *recordingTransport) Do(req *http.Request) (*http.Response, error) {
+	t.sent++
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestDryRunPolicyStrict(t *testing.T) {
+	dryrun.SetMode(dryrun.Strict)
+	t.Cleanup(func() { dryrun.SetMode(dryrun.Off) })
+
+	tests := []struct {
+		method   string
+		readOnly bool
+		sent     bool
+	}{
+		{http.MethodGet, false, true},
+		{http.MethodHead, false, true},
+		{http.MethodPut, false, false},
+		{http.MethodPatch, false, false},
+		{http.MethodDelete, false, false},
+		{http.MethodPost, false, false},
+		{http.MethodPut, true, true},
+		{http.MethodPatch, true, true},
+		{http.MethodDelete, true, true},
+		{http.MethodPost, true, true},
+	}
+
+	for _, tt := range tests {
+		transport := &recordingTransport{}
+		pipeline := runtime.NewPipeline("test", "v0.0.0", runtime.PipelineOptions{PerCall: []policy.Policy{dryRunPolicy{}}}, &policy.ClientOptions{Transport: transport})
+
+		ctx := context.Background()
+		if tt.readOnly {
+			ctx = ReadOnly(ctx)
+		}
+		req, err := runtime.NewRequest(ctx, tt.method, "https://management.azure.com/subscriptions/s/resourceGroups/rg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.method != http.MethodGet && tt.method != http.MethodHead {
+			if err := req.SetBody(streaming.NopCloser(strings.NewReader(`{"location":"westus"}`)), "application/json"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, err = pipeline.Do(req)
+		if sent := transport.sent > 0; sent != tt.sent {
+			t.Errorf("%v (read only %v): sent %v, want %v", tt.method, tt.readOnly, sent, tt.sent)
+		}
+		if !tt.sent && !errors.Is(err, dryrun.ErrDryRun) {
+			t.Errorf("%v (read only %v): err %v, want %v", tt.method, tt.readOnly, err, dryrun.ErrDryRun)
+		}
+		if tt.sent && err != nil {
+			t.Errorf("%v (read only %v): unexpected err %v", tt.method, tt.readOnly, err)
+		}
+	}
+}
+
+func TestDryRunPolicyPreview(t *testing.T) {
+	dryrun.SetMode(dryrun.Preview)
+	t.Cleanup(func() {
+		dryrun.SetMode(dryrun.Off)
+		dryrun.SetConfirmFunc(nil)
+	})
+
+	var asked dryrun.Request
+	dryrun.SetConfirmFunc(func(req dryrun.Request) error {
+		asked = req
+		return dryrun.ErrDeclined
+	})
+
+	transport := &recordingTransport{}
+	pipeline := runtime.NewPipeline("test", "v0.0.0", runtime.PipelineOptions{PerCall: []policy.Policy{dryRunPolicy{}}}, &policy.ClientOptions{Transport: transport})
+	req, err := runtime.NewRequest(context.Background(), http.MethodPut, "https://management.azure.com/subscriptions/s/resourceGroups/rg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.SetBody(streaming.NopCloser(strings.NewReader(`{"location":"westus"}`)), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pipeline.Do(req); !errors.Is(err, dryrun.ErrDeclined) {
+		t.Errorf("err %v, want %v", err, dryrun.ErrDeclined)
+	}
+	if transport.sent != 0 {
+		t.Errorf("declined request was sent")
+	}
+	if asked.Method != http.MethodPut || asked.Body != `{"location":"westus"}` {
+		t.Errorf("asked to confirm %+v", asked)
+	}
+}
@@ -0,0 +1,78 @@
+package azclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+// Cloud returns the cloud of the active profile.
+func Cloud() cloud.Configuration {
+	profile := config.ActiveProfile()
+	switch strings.ToLower(profile.Cloud) {
+	case "azuregovernment":
+		return cloud.AzureGovernment
+	case "azurechina":
+		return cloud.AzureChina
+	}
+
+	if isCustomCloud(profile) {
+		endpoints := profile.Endpoints
+		audience := endpoints.Audience
+		if audience == "" {
+			audience = endpoints.ResourceManager
+		}
+		return cloud.Configuration{
+			ActiveDirectoryAuthorityHost: endpoints.Authority,
+			Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+				cloud.ResourceManager: {
+					Audience: audience,
+					Endpoint: endpoints.ResourceManager,
+				},
+			},
+		}
+	}
+
+	return cloud.AzurePublic
+}
+
+func isCustomCloud(profile config.Profile) bool {
+	return strings.EqualFold(profile.Cloud, config.CustomCloud)
+}
+
+// ResourceManagerEndpoint returns the Azure Resource Manager URL of the
+// active profile's cloud, without a trailing slash.
+func ResourceManagerEndpoint() string {
+	return strings.TrimSuffix(Cloud().Services[cloud.ResourceManager].Endpoint, "/")
+}
+
+// Portal hosts of the built-in clouds, keyed by lower case cloud name
+var portals = map[string]string{
+	"":                "https://portal.azure.com",
+	"azurepublic":     "https://portal.azure.com",
+	"azuregovernment": "https://portal.azure.us",
+	"azurechina":      "https://portal.azure.cn",
+}
+
+// PortalURL returns a link to the resource resourceID in the portal of the
+// active profile's cloud, or an error for custom clouds without a portal.
+func PortalURL(resourceID string) (string, error) {
+	profile := config.ActiveProfile()
+	portal, ok := portals[strings.ToLower(profile.Cloud)]
+	if isCustomCloud(profile) {
+		portal, ok = profile.Endpoints.Portal, profile.Endpoints.Portal != ""
+	}
+	if !ok {
+		return "", fmt.Errorf("profile %v has no portal endpoint", profile.Name)
+	}
+
+	tenant := ""
+	if profile.Tenant != "" {
+		tenant = "@" + profile.Tenant + "/"
+	}
+
+	return fmt.Sprintf("%v/#%vresource%v", strings.TrimSuffix(portal, "/"), tenant, resourceID), nil
+}
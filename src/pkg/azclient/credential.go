@@ -0,0 +1,250 @@
+package azclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+var (
+	credentialMu sync.Mutex
+	credential   azcore.TokenCredential
+	// The profile credential was created for
+	credentialProfile string
+	// Whether the user signed in to credential, see Login
+	signedIn bool
+	// Shows the instructions of the device code flow, see SetDeviceCodeFunc
+	deviceCodeFunc func(message string)
+)
+
+// Credential returns the credential of the active profile. It is created
+// once and shared by every client until the profile changes.
+func Credential() (azcore.TokenCredential, error) {
+	profile := config.ActiveProfile()
+	key := fmt.Sprintf("%+v", profile)
+
+	credentialMu.Lock()
+	defer credentialMu.Unlock()
+	if credential != nil && credentialProfile == key {
+		return credential, nil
+	}
+
+	cred, err := newCredential(profile)
+	if err != nil {
+		return nil, fmt.Errorf("%v credential: %w", credentialType(profile), err)
+	}
+	credential, credentialProfile, signedIn = cred, key, false
+
+	return credential, nil
+}
+
+func newCredential(profile config.Profile) (azcore.TokenCredential, error) {
+	clientOptions := policy.ClientOptions{Cloud: Cloud()}
+	// Instance discovery only knows the public and sovereign clouds
+	disableInstanceDiscovery := isCustomCloud(profile)
+
+	switch credentialType(profile) {
+	case "azurecli":
+		return &cliCredential{tenant: profile.Tenant}, nil
+
+	case "devicecode":
+		return azidentity.NewDeviceCodeCredential(&azidentity.DeviceCodeCredentialOptions{
+			ClientOptions:            clientOptions,
+			ClientID:                 profile.ClientID,
+			DisableInstanceDiscovery: disableInstanceDiscovery,
+			TenantID:                 profile.Tenant,
+			UserPrompt: func(ctx context.Context, message azidentity.DeviceCodeMessage) error {
+				credentialMu.Lock()
+				prompt := deviceCodeFunc
+				credentialMu.Unlock()
+
+				if prompt == nil {
+					fmt.Fprintln(os.Stderr, message.Message)
+				} else {
+					prompt(message.Message)
+				}
+				return nil
+			},
+		})
+
+	case "browser":
+		return azidentity.NewInteractiveBrowserCredential(&azidentity.InteractiveBrowserCredentialOptions{
+			ClientOptions:            clientOptions,
+			ClientID:                 profile.ClientID,
+			DisableInstanceDiscovery: disableInstanceDiscovery,
+			TenantID:                 profile.Tenant,
+		})
+
+	case "serviceprincipal":
+		if profile.Certificate == "" {
+			return azidentity.NewClientSecretCredential(profile.Tenant, profile.ClientID, os.ExpandEnv(profile.ClientSecret), &azidentity.ClientSecretCredentialOptions{
+				ClientOptions:            clientOptions,
+				DisableInstanceDiscovery: disableInstanceDiscovery,
+			})
+		}
+
+		data, err := os.ReadFile(os.ExpandEnv(profile.Certificate))
+		if err != nil {
+			return nil, err
+		}
+		var password []byte
+		if profile.CertificatePassword != "" {
+			password = []byte(os.ExpandEnv(profile.CertificatePassword))
+		}
+		certs, key, err := azidentity.ParseCertificates(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", profile.Certificate, err)
+		}
+		return azidentity.NewClientCertificateCredential(profile.Tenant, profile.ClientID, certs, key, &azidentity.ClientCertificateCredentialOptions{
+			ClientOptions:            clientOptions,
+			DisableInstanceDiscovery: disableInstanceDiscovery,
+		})
+
+	case "workloadidentity":
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions:            clientOptions,
+			ClientID:                 profile.ClientID,
+			DisableInstanceDiscovery: disableInstanceDiscovery,
+			TenantID:                 profile.Tenant,
+			TokenFilePath:            os.ExpandEnv(profile.TokenFile),
+		})
+
+	case "managedidentity":
+		options := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOptions}
+		if profile.ClientID != "" {
+			options.ID = azidentity.ClientID(profile.ClientID)
+		}
+		return azidentity.NewManagedIdentityCredential(options)
+
+	default:
+		if len(profile.Env) > 0 {
+			return newDefaultCredentialWithEnv(profile, clientOptions, disableInstanceDiscovery)
+		}
+		return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+			ClientOptions:            clientOptions,
+			DisableInstanceDiscovery: disableInstanceDiscovery,
+			TenantID:                 profile.Tenant,
+		})
+	}
+}
+
+// newDefaultCredentialWithEnv returns the default credential of a profile
+// setting env: the environment variables, workload identity and the az login
+// with the profile's env, which the az login of DefaultAzureCredential
+// doesn't run with. Managed identity needs credential managedidentity then.
+func newDefaultCredentialWithEnv(profile config.Profile, clientOptions policy.ClientOptions, disableInstanceDiscovery bool) (azcore.TokenCredential, error) {
+	var sources []azcore.TokenCredential
+	// Like DefaultAzureCredential, skip the credentials that aren't set up
+	if env, err := azidentity.NewEnvironmentCredential(&azidentity.EnvironmentCredentialOptions{
+		ClientOptions:            clientOptions,
+		DisableInstanceDiscovery: disableInstanceDiscovery,
+	}); err == nil {
+		sources = append(sources, env)
+	}
+	if workload, err := azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+		ClientOptions:            clientOptions,
+		DisableInstanceDiscovery: disableInstanceDiscovery,
+		TenantID:                 profile.Tenant,
+	}); err == nil {
+		sources = append(sources, workload)
+	}
+	sources = append(sources, &cliCredential{tenant: profile.Tenant})
+
+	return azidentity.NewChainedTokenCredential(sources, nil)
+}
+
+// cliCredential is the az login. Unlike azidentity.AzureCLICredential it
+// runs az like every other az command, with the configured path and the
+// environment of azcli.env and the active profile, so Resource Manager
+// requests and az commands use the same login.
+type cliCredential struct {
+	// Tenant to get tokens for, the login's default tenant when empty
+	tenant string
+}
+
+func (c *cliCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	if len(options.Scopes) != 1 {
+		return azcore.AccessToken{}, fmt.Errorf("az login: expected one scope, got %v", len(options.Scopes))
+	}
+	tenant := c.tenant
+	if options.TenantID != "" {
+		tenant = options.TenantID
+	}
+
+	token, err := azcli.GetAccessToken(ctx, strings.TrimSuffix(options.Scopes[0], "/.default"), tenant)
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	return azcore.AccessToken{Token: token.Token, ExpiresOn: token.ExpiresOn}, nil
+}
+
+func credentialType(profile config.Profile) string {
+	if profile.Credential == "" {
+		return "default"
+	}
+	return strings.ToLower(profile.Credential)
+}
+
+// SetDeviceCodeFunc sets the function showing the instructions of the device
+// code flow, such as the code to enter. It is called from the goroutine
+// signing in. Without one the instructions are printed to stderr.
+func SetDeviceCodeFunc(f func(message string)) {
+	credentialMu.Lock()
+	defer credentialMu.Unlock()
+	deviceCodeFunc = f
+}
+
+// NeedsLogin reports whether the credential of the active profile asks the
+// user to sign in and hasn't been signed in to with Login yet. Requests made
+// with such a credential block until the user signed in, so Login has to run
+// first, off the UI goroutine.
+func NeedsLogin() bool {
+	switch credentialType(config.ActiveProfile()) {
+	case "devicecode", "browser":
+	default:
+		return false
+	}
+
+	if _, err := Credential(); err != nil {
+		// Reported by whatever uses the credential next
+		return false
+	}
+
+	credentialMu.Lock()
+	defer credentialMu.Unlock()
+	return !signedIn
+}
+
+// Login signs in to the credential of the active profile by requesting a
+// Resource Manager token, which the credential keeps for later requests.
+func Login(ctx context.Context) error {
+	cred, err := Credential()
+	if err != nil {
+		return err
+	}
+
+	audience := Cloud().Services[cloud.ResourceManager].Audience
+	_, err = cred.GetToken(ctx, policy.TokenRequestOptions{
+		Scopes: []string{strings.TrimSuffix(audience, "/") + "/.default"},
+	})
+	if err != nil {
+		return fmt.Errorf("%v credential: %w", credentialType(config.ActiveProfile()), err)
+	}
+
+	credentialMu.Lock()
+	defer credentialMu.Unlock()
+	if credential == cred {
+		signedIn = true
+	}
+	return nil
+}
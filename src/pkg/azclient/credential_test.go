@@ -0,0 +1,63 @@
+package azclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+func TestAzureCLICredentialUsesProfileEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake az is a shell script")
+	}
+
+	// Answers with the config dir and arguments as the token
+	dir := t.TempDir()
+	az := filepath.Join(dir, "az")
+	script := "#!/bin/sh\nprintf '{\"accessToken\": \"%s %s\", \"expires_on\": 4102444800}' \"$AZURE_CONFIG_DIR\" \"$*\"\n"
+	if err := os.WriteFile(az, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, credential := range []string{"azurecli", "default"} {
+		path := filepath.Join(dir, "aztui.yaml")
+		data := "azcli:\n  path: " + az + "\ndefaultProfile: customer\nprofiles:\n  - profile: customer\n    tenant: contoso\n    credential: " + credential + "\n    env:\n      AZURE_CONFIG_DIR: /customer\n"
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.LoadConfig(path); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { config.LoadConfig("") })
+		// Keep the environment credential out of the default chain
+		for _, name := range []string{"AZURE_TENANT_ID", "AZURE_CLIENT_ID", "AZURE_CLIENT_SECRET", "AZURE_CLIENT_CERTIFICATE_PATH", "AZURE_USERNAME", "AZURE_FEDERATED_TOKEN_FILE"} {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+
+		cred, err := Credential()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{
+			Scopes: []string{"https://management.azure.com/.default"},
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", credential, err)
+		}
+
+		want := "/customer account get-access-token --output json --resource https://management.azure.com --tenant contoso"
+		if token.Token != want {
+			t.Errorf("%v token = %q, want %q", credential, token.Token, want)
+		}
+		if token.ExpiresOn.Unix() != 4102444800 {
+			t.Errorf("%v token expires on %v", credential, token.ExpiresOn)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package azclient
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HTTP Archive 1.2, as read by browsers and support tooling. Only what
+// traces record is filled in, bodies aren't recorded.
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	HTTPVersion string    `json:"httpVersion"`
+	Cookies     []harPair `json:"cookies"`
+	Headers     []harPair `json:"headers"`
+	QueryString []harPair `json:"queryString"`
+	HeadersSize int       `json:"headersSize"`
+	BodySize    int       `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// WriteHAR writes traces to w as an HTTP Archive.
+func WriteHAR(w io.Writer, traces []Trace) error {
+	archive := har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "aztui", Version: "1"},
+		Entries: []harEntry{},
+	}}
+
+	for _, t := range traces {
+		query := []harPair{}
+		if u, err := url.Parse(t.URL); err == nil {
+			query = harPairs(u.Query())
+		}
+		milliseconds := float64(t.Duration) / float64(time.Millisecond)
+
+		archive.Log.Entries = append(archive.Log.Entries, harEntry{
+			StartedDateTime: t.Started.Format(time.RFC3339Nano),
+			Time:            milliseconds,
+			Request: harRequest{
+				Method:      t.Method,
+				URL:         t.URL,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harPair{},
+				Headers:     harPairs(t.RequestHeaders),
+				QueryString: query,
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Response: harResponse{
+				Status:      t.Status,
+				StatusText:  http.StatusText(t.Status),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harPair{},
+				Headers:     harPairs(t.ResponseHeaders),
+				Content:     harContent{MimeType: "application/json"},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Timings: harTimings{Send: 0, Wait: milliseconds, Receive: 0},
+			Comment: t.Err,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
+
+// harPairs flattens header or query values, sorted by name.
+func harPairs(values map[string][]string) []harPair {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []harPair{}
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, harPair{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
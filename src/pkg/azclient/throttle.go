@@ -0,0 +1,129 @@
+package azclient
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+)
+
+// retryOptions are the retry options of every client, see config.ARM.
+func retryOptions() policy.RetryOptions {
+	arm := config.Current().ARM
+	return policy.RetryOptions{
+		MaxRetries:    int32(arm.MaxRetries),
+		RetryDelay:    arm.RetryDelay,
+		MaxRetryDelay: arm.MaxRetryDelay,
+	}
+}
+
+// RateLimit is what the last ARM response said about throttling.
+type RateLimit struct {
+	SubscriptionID string
+	// Subscription reads left in the current window, -1 when the response
+	// didn't say
+	RemainingReads int
+	// Set while a throttled request waits to be retried
+	ThrottledUntil time.Time
+}
+
+var throttle = struct {
+	sync.Mutex
+	// Semaphores of subscriptions by lowercase ID, see acquire
+	slots     map[string]chan struct{}
+	rateLimit RateLimit
+}{
+	slots:     map[string]chan struct{}{},
+	rateLimit: RateLimit{RemainingReads: -1},
+}
+
+// CurrentRateLimit returns the rate limit reported by the last ARM response.
+func CurrentRateLimit() RateLimit {
+	throttle.Lock()
+	defer throttle.Unlock()
+	return throttle.rateLimit
+}
+
+// subscriptionOf returns the subscription ID in a Resource Manager URL path,
+// or "" for requests outside subscriptions such as listing them.
+func subscriptionOf(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "subscriptions") {
+			return strings.ToLower(parts[i+1])
+		}
+	}
+	return ""
+}
+
+// acquire takes one of the concurrent request slots of subscription, or
+// returns nil without a limit. The slot is returned by sending to it.
+func acquire(req *policy.Request, subscription string) (chan struct{}, error) {
+	limit := config.Current().ARM.MaxConcurrentRequests
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	throttle.Lock()
+	slots, ok := throttle.slots[subscription]
+	if !ok || cap(slots) != limit {
+		// Requests holding a slot of a previous limit return it there
+		slots = make(chan struct{}, limit)
+		for i := 0; i < limit; i++ {
+			slots <- struct{}{}
+		}
+		throttle.slots[subscription] = slots
+	}
+	throttle.Unlock()
+
+	select {
+	case <-slots:
+		return slots, nil
+	case <-req.Raw().Context().Done():
+		return nil, req.Raw().Context().Err()
+	}
+}
+
+// throttlePolicy limits the requests sent at the same time per subscription
+// and keeps track of the rate limit headers of every try.
+type throttlePolicy struct{}
+
+func (throttlePolicy) Do(req *policy.Request) (*http.Response, error) {
+	subscription := subscriptionOf(req.Raw().URL.Path)
+	slots, err := acquire(req, subscription)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := req.Next()
+	if slots != nil {
+		slots <- struct{}{}
+	}
+	if resp == nil {
+		return resp, err
+	}
+
+	throttled := resp.StatusCode == http.StatusTooManyRequests
+	// Seconds, the only form ARM sends
+	retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+
+	throttle.Lock()
+	if remaining, err := strconv.Atoi(resp.Header.Get("x-ms-ratelimit-remaining-subscription-reads")); err == nil {
+		throttle.rateLimit.SubscriptionID = subscription
+		throttle.rateLimit.RemainingReads = remaining
+	}
+	if throttled {
+		throttle.rateLimit.ThrottledUntil = time.Now().Add(time.Duration(retryAfter) * time.Second)
+	}
+	throttle.Unlock()
+
+	if throttled {
+		logger.Warn("ARM request throttled", "method", req.Raw().Method, "url", req.Raw().URL.String(), "retryAfter", retryAfter)
+	}
+	return resp, err
+}
@@ -0,0 +1,164 @@
+package azclient
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+)
+
+// Trace is an ARM request recorded while tracing is on. Retries of a request
+// are recorded as one, with the final status and the total duration.
+type Trace struct {
+	Started  time.Time
+	Method   string
+	URL      string
+	Duration time.Duration
+	// HTTP status, 0 when no response was received
+	Status int
+	// Error of a request that got no response, such as a network error or a
+	// request held back by dry-run
+	Err string
+	// x-ms-request-id of the response, which support asks for
+	RequestID string
+	// Headers with credentials redacted, see redactHeaders
+	RequestHeaders  http.Header
+	ResponseHeaders http.Header
+}
+
+// RateLimits returns the throttling headers of the response: Retry-After
+// and the remaining x-ms-ratelimit-* quotas.
+func (t Trace) RateLimits() map[string]string {
+	limits := map[string]string{}
+	for name, values := range t.ResponseHeaders {
+		lower := strings.ToLower(name)
+		if lower == "retry-after" || strings.HasPrefix(lower, "x-ms-ratelimit-") {
+			limits[lower] = strings.Join(values, ", ")
+		}
+	}
+	return limits
+}
+
+// Headers whose values are replaced in traces, so exports can be shared
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+var traces = struct {
+	sync.Mutex
+	list []Trace
+	// Set with SetTracing, config.Current().Trace.Enabled applies when nil
+	enabled *bool
+	// Called with every new trace, see SetTraceFunc
+	traceFunc func(Trace)
+}{}
+
+// Tracing reports whether ARM requests are recorded.
+func Tracing() bool {
+	traces.Lock()
+	defer traces.Unlock()
+	if traces.enabled != nil {
+		return *traces.enabled
+	}
+	return config.Current().Trace.Enabled
+}
+
+// SetTracing starts or stops recording ARM requests, whatever the
+// configuration says.
+func SetTracing(enabled bool) {
+	traces.Lock()
+	defer traces.Unlock()
+	traces.enabled = &enabled
+}
+
+// Traces returns the recorded requests, oldest first.
+func Traces() []Trace {
+	traces.Lock()
+	defer traces.Unlock()
+	return append([]Trace{}, traces.list...)
+}
+
+// ClearTraces drops the recorded requests.
+func ClearTraces() {
+	traces.Lock()
+	defer traces.Unlock()
+	traces.list = nil
+}
+
+// SetTraceFunc sets a function called with every request recorded from now
+// on, on the goroutine that made it.
+func SetTraceFunc(f func(Trace)) {
+	traces.Lock()
+	defer traces.Unlock()
+	traces.traceFunc = f
+}
+
+func recordTrace(t Trace) {
+	traces.Lock()
+	traces.list = append(traces.list, t)
+	if max := config.Current().Trace.MaxRequests; max > 0 && len(traces.list) > max {
+		traces.list = append([]Trace{}, traces.list[len(traces.list)-max:]...)
+	}
+	f := traces.traceFunc
+	traces.Unlock()
+
+	if f != nil {
+		f(t)
+	}
+}
+
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range redactedHeaders {
+		values := redacted.Values(name)
+		for i, value := range values {
+			// Keep the scheme of credentials, it tells a bearer token from
+			// others. Cookies are dropped whole, "name=value; Path=/" has
+			// no scheme.
+			scheme, _, found := strings.Cut(value, " ")
+			if found && strings.HasSuffix(name, "Authorization") {
+				values[i] = scheme + " REDACTED"
+			} else {
+				values[i] = "REDACTED"
+			}
+		}
+	}
+	return redacted
+}
+
+// tracePolicy records requests while tracing is on.
+type tracePolicy struct{}
+
+func (tracePolicy) Do(req *policy.Request) (*http.Response, error) {
+	if !Tracing() {
+		return req.Next()
+	}
+
+	raw := req.Raw()
+	t := Trace{
+		Started: time.Now(),
+		Method:  raw.Method,
+		URL:     raw.URL.String(),
+		// Tries are sent as copies, the bearer token is only added to those.
+		// Redacting still keeps credentials set by callers out of exports.
+		RequestHeaders: redactHeaders(raw.Header),
+	}
+
+	resp, err := req.Next()
+	t.Duration = time.Since(t.Started)
+	if resp != nil {
+		t.Status = resp.StatusCode
+		t.RequestID = resp.Header.Get("x-ms-request-id")
+		t.ResponseHeaders = redactHeaders(resp.Header)
+	}
+	if err != nil {
+		t.Err = err.Error()
+	}
+
+	logger.Debug("ARM request", "method", t.Method, "url", t.URL, "status", t.Status, "duration", t.Duration, "requestID", t.RequestID)
+	recordTrace(t)
+	return resp, err
+}
@@ -0,0 +1,96 @@
+package azclient
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// cookieTransport answers with a cookie and keeps the headers it was sent.
+type cookieTransport struct {
+	sent http.Header
+}
+
+func (t *cookieTransport) Do(req *http.Request) (*http.Response, error) {
+	t.sent = req.Header.Clone()
+	header := http.Header{}
+	header.Set("Set-Cookie", "session=response-secret; Path=/; HttpOnly")
+	header.Set("x-ms-request-id", "id")
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+}
+
+// credentialPolicy adds credentials to requests, like the bearer token
+// policy does.
+type credentialPolicy struct{}
+
+func (credentialPolicy) Do(req *policy.Request) (*http.Response, error) {
+	req.Raw().Header.Set("Authorization", "Bearer token-secret")
+	req.Raw().Header.Set("Cookie", "a=cookie-secret; b=other-secret")
+	req.Raw().Header.Set("Proxy-Authorization", "Basic proxy-secret")
+	return req.Next()
+}
+
+func TestTraceRedactsCredentials(t *testing.T) {
+	SetTracing(true)
+	ClearTraces()
+	t.Cleanup(func() {
+		SetTracing(false)
+		ClearTraces()
+	})
+
+	transport := &cookieTransport{}
+	pipeline := runtime.NewPipeline("test", "v0.0.0", runtime.PipelineOptions{PerCall: []policy.Policy{credentialPolicy{}, tracePolicy{}}}, &policy.ClientOptions{Transport: transport})
+	req, err := runtime.NewRequest(context.Background(), http.MethodGet, "https://management.azure.com/subscriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pipeline.Do(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// The request sent keeps its credentials
+	if got := transport.sent.Get("Authorization"); got != "Bearer token-secret" {
+		t.Errorf("sent Authorization %q", got)
+	}
+	if got := req.Raw().Header.Get("Cookie"); got != "a=cookie-secret; b=other-secret" {
+		t.Errorf("request Cookie %q", got)
+	}
+
+	recorded := Traces()
+	if len(recorded) != 1 {
+		t.Fatalf("%v traces, want 1", len(recorded))
+	}
+	trace := recorded[0]
+	tests := []struct {
+		header http.Header
+		name   string
+		want   string
+	}{
+		{trace.RequestHeaders, "Authorization", "Bearer REDACTED"},
+		{trace.RequestHeaders, "Proxy-Authorization", "Basic REDACTED"},
+		{trace.RequestHeaders, "Cookie", "REDACTED"},
+		{trace.ResponseHeaders, "Set-Cookie", "REDACTED"},
+		{trace.ResponseHeaders, "x-ms-request-id", "id"},
+	}
+	for _, tt := range tests {
+		if got := tt.header.Get(tt.name); got != tt.want {
+			t.Errorf("traced %v %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	var har bytes.Buffer
+	if err := WriteHAR(&har, recorded); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(har.String(), "secret") {
+		t.Errorf("credentials exported:\n%v", har.String())
+	}
+	if !strings.Contains(har.String(), "Bearer REDACTED") {
+		t.Errorf("Authorization missing from the export:\n%v", har.String())
+	}
+}
@@ -0,0 +1,143 @@
+// Package cache keeps listings of Azure resources on disk, so views can show
+// them before they are fetched again.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Key identifies a listing, such as the virtual machines of a resource
+// group.
+type Key struct {
+	// Tenant, or profile, the listing was made with
+	Tenant       string `json:"tenant"`
+	Subscription string `json:"subscription,omitempty"`
+	// Resource group, or another scope within the subscription
+	Scope string `json:"scope,omitempty"`
+	// What is listed, e.g. a resource type
+	Type string `json:"type"`
+}
+
+// entry is the content of a cache file.
+type entry struct {
+	Key     Key             `json:"key"`
+	Fetched time.Time       `json:"fetched"`
+	Items   json.RawMessage `json:"items"`
+}
+
+var (
+	mu       sync.Mutex
+	disabled bool
+)
+
+// SetEnabled turns the cache on or off, e.g. for --no-cache. Listings are
+// neither read nor written while it is off.
+func SetEnabled(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	disabled = !enabled
+}
+
+// Enabled reports whether listings are cached.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return !disabled
+}
+
+// Dir returns the directory listings are kept in, aztui/listings in
+// $XDG_CACHE_HOME or ~/.cache.
+func Dir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "aztui", "listings"), nil
+}
+
+func path(key Key) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// Load reads the listing of key into items and returns when it was fetched.
+// It returns false when there is no usable listing.
+func Load(key Key, items any) (time.Time, bool) {
+	if !Enabled() {
+		return time.Time{}, false
+	}
+
+	file, err := path(key)
+	if err != nil {
+		return time.Time{}, false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal(e.Items, items); err != nil {
+		return time.Time{}, false
+	}
+	return e.Fetched, true
+}
+
+// Store writes items as the listing of key, fetched now.
+func Store(key Key, items any) error {
+	if !Enabled() {
+		return nil
+	}
+
+	file, err := path(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(entry{Key: key, Fetched: time.Now(), Items: data})
+	if err != nil {
+		return err
+	}
+
+	// Listings name subscriptions and resources, keep them private
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	// Written aside and renamed, so a listing is never read half written
+	temp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
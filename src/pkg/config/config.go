@@ -1,39 +1,342 @@
 package config
 
 import (
+	_ "embed"
 	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// The configuration used when there is no user configuration file, and the
+// base user configuration files are merged on top of.
+//
+//go:embed default.yaml
+var defaultConfig []byte
+
 type Action struct {
-	Action      string `yaml:"action"`
-	TakeFocus   bool   `yaml:"takeFocus"`
+	Action    string `yaml:"action"`
+	TakeFocus bool   `yaml:"takeFocus,omitempty"`
+	// Key binding, see ParseKeySequence
 	Key         string `yaml:"key"`
-	Width       int    `yaml:"width"`
-	Description string `yaml:"description"`
+	Width       int    `yaml:"width,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// Set in a user configuration file to drop the default binding of the
+	// action
+	Remove bool `yaml:"remove,omitempty"`
+	// The action acts on every row selected in a list view rather than the
+	// current one, for actions that support it
+	Multiple bool `yaml:"multiple,omitempty"`
 }
 
 type View struct {
 	Name    string   `yaml:"view"`
 	Actions []Action `yaml:"actions"`
+	// How often list views are fetched again while shown, e.g. 30s. They
+	// are only fetched when focused or refreshed when zero.
+	RefreshInterval time.Duration `yaml:"refreshInterval,omitempty"`
+}
+
+// AzCLI configures how the az command line is run.
+type AzCLI struct {
+	// az executable, looked up on PATH when empty
+	Path string `yaml:"path"`
+	// Variables added to the inherited environment, e.g. AZURE_CONFIG_DIR
+	// to use a separate login. Values are expanded, so $HOME can be used.
+	Env map[string]string `yaml:"env"`
+	// Arguments added to every command that doesn't set them itself, e.g.
+	// --only-show-errors
+	Args []string `yaml:"args"`
+	// Output format of commands run from command lists. Only json output is
+	// rendered as tables and trees, so it's the default.
+	Output string `yaml:"output"`
+	// Commands still running after this long are killed, e.g. "10m". No
+	// limit when unset.
+	Timeout time.Duration `yaml:"timeout"`
+	// Commands run at the same time against the rows selected in a list
+	// view, one at a time when 0
+	MaxConcurrentCommands int `yaml:"maxConcurrentCommands"`
+}
+
+// History configures the history of executed commands.
+type History struct {
+	// Commands kept, the oldest are dropped once there are more. No limit
+	// when 0.
+	MaxEntries int `yaml:"maxEntries"`
+	// Whether the output of commands is kept, it can contain secrets
+	Output bool `yaml:"output"`
 }
 
+// Log configures the log file.
+type Log struct {
+	// Log file, aztui.log in $XDG_STATE_HOME/aztui (~/.local/state/aztui)
+	// when empty
+	Path string `yaml:"path"`
+	// One of LogLevels, --log-level overrides it
+	Level string `yaml:"level"`
+	// The file is rotated once it grows past this many megabytes, never
+	// when 0
+	MaxSizeMB int `yaml:"maxSizeMB"`
+	// Rotated files kept, aztui.log.1 being the newest
+	MaxBackups int `yaml:"maxBackups"`
+}
+
+// ARM configures how Resource Manager requests are retried and throttled.
+type ARM struct {
+	// Retries of throttled and failed requests, 3 when 0, none when negative
+	MaxRetries int `yaml:"maxRetries"`
+	// Delay before the first retry, doubling with every retry up to
+	// maxRetryDelay. Throttled requests wait as long as their Retry-After
+	// header asks instead, unless that is longer than maxRetryDelay.
+	RetryDelay    time.Duration `yaml:"retryDelay"`
+	MaxRetryDelay time.Duration `yaml:"maxRetryDelay"`
+	// Requests sent at the same time per subscription, no limit when 0
+	MaxConcurrentRequests int `yaml:"maxConcurrentRequests"`
+	// Subscriptions listed at the same time by views across subscriptions,
+	// and power states fetched at the same time for the virtual machines of
+	// a resource group, one at a time when 0
+	MaxConcurrentSubscriptions int `yaml:"maxConcurrentSubscriptions"`
+	// The status bar warns once fewer subscription reads than this remain
+	LowRateLimit int `yaml:"lowRateLimit"`
+}
+
+// Trace configures the recording of ARM requests shown in the requests view.
+type Trace struct {
+	// Record requests from the start, --trace turns it on too
+	Enabled bool `yaml:"enabled"`
+	// Requests kept, the oldest are dropped first
+	MaxRequests int `yaml:"maxRequests"`
+}
+
+// LogLevels are the levels of Log.Level, from the most verbose.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
 type Config struct {
 	Views []View `yaml:"views"`
+	// How long to wait for the next key of a key sequence such as "g g"
+	KeyTimeout time.Duration `yaml:"keyTimeout"`
+	AzCLI      AzCLI         `yaml:"azcli"`
+	History    History       `yaml:"history"`
+	Theme      Theme         `yaml:"theme"`
+	Profiles   []Profile     `yaml:"profiles"`
+	// Profile used unless --profile selects another one
+	DefaultProfile string `yaml:"defaultProfile"`
+	Log            Log    `yaml:"log"`
+	Trace          Trace  `yaml:"trace"`
+	ARM            ARM    `yaml:"arm"`
 }
 
-var GConfig Config
+// current is the configuration last loaded, replaced as a whole by
+// LoadConfig when the file is reloaded while other goroutines read it
+var current atomic.Pointer[Config]
 
+func init() {
+	current.Store(&Config{})
+}
+
+// Current returns the configuration last loaded by LoadConfig. It is safe to
+// call from any goroutine. The configuration is shared and must not be
+// modified.
+func Current() *Config {
+	return current.Load()
+}
+
+// Path returns the user configuration file, $AZTUI_CONFIG_PATH or
+// aztui.yaml in $XDG_CONFIG_HOME, ~/.config when unset. The file doesn't have
+// to exist.
+func Path() string {
+	if path := os.Getenv("AZTUI_CONFIG_PATH"); path != "" {
+		return path
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+
+	return filepath.Join(configDir, "aztui.yaml")
+}
+
+// LoadConfig loads the embedded default configuration with configFile merged
+// on top, see Merge. configFile is optional, only the defaults are loaded
+// when it's empty or doesn't exist.
 func LoadConfig(configFile string) (Config, error) {
-	file, err := os.ReadFile(configFile)
+	merged, err := mergedConfig(configFile)
 	if err != nil {
 		return Config{}, err
 	}
 
 	var config Config
-	err = yaml.Unmarshal(file, &config)
-	GConfig = config
+	if err := merged.Decode(&config); err != nil {
+		return Config{}, err
+	}
+	current.Store(&config)
+
+	return config, nil
+}
+
+// Dump returns the effective configuration, the defaults with configFile
+// merged on top, as YAML.
+func Dump(configFile string) ([]byte, error) {
+	merged, err := mergedConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(merged)
+}
+
+func mergedConfig(configFile string) (*yaml.Node, error) {
+	if configFile == "" {
+		return mergeDefaults(nil)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return mergeDefaults(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	return mergeDefaults(data)
+}
+
+func mergeDefaults(data []byte) (*yaml.Node, error) {
+	var merged yaml.Node
+	if err := yaml.Unmarshal(defaultConfig, &merged); err != nil {
+		return nil, err
+	}
+
+	var user yaml.Node
+	if err := yaml.Unmarshal(data, &user); err != nil {
+		return nil, err
+	}
+
+	return Merge(&merged, &user), nil
+}
+
+// LoadMerged returns the configuration resulting from merging the user
+// configuration data on top of the defaults, without making it the current
+// configuration.
+func LoadMerged(data []byte) (Config, error) {
+	merged, err := mergeDefaults(data)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	err = merged.Decode(&config)
 	return config, err
 }
+
+// Merge merges the overlay YAML document on top of base, modifying base.
+// Mappings are merged key by key, the views list by view name, action lists
+// by action name and the profiles list by profile name, so a user
+// configuration only needs the settings it changes. An item with remove: true
+// drops the item from base. Any other value in overlay replaces the one in
+// base.
+func Merge(base, overlay *yaml.Node) *yaml.Node {
+	if base == nil {
+		return overlay
+	}
+	// Empty documents decode to zero nodes
+	if overlay == nil || overlay.Kind == 0 || (overlay.Kind == yaml.DocumentNode && len(overlay.Content) == 0) {
+		return base
+	}
+	if base.Kind != overlay.Kind {
+		return overlay
+	}
+
+	// Empty defaults such as "theme: {}" take the style of what's merged
+	// into them, so dumps aren't squeezed onto one line
+	if len(base.Content) == 0 {
+		base.Style = overlay.Style
+	}
+
+	switch base.Kind {
+	case yaml.DocumentNode:
+		if len(base.Content) == 0 {
+			return overlay
+		}
+		base.Content[0] = Merge(base.Content[0], overlay.Content[0])
+		return base
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			if j := mappingIndex(base, key.Value); j != -1 {
+				base.Content[j+1] = Merge(base.Content[j+1], value)
+			} else {
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		return base
+
+	case yaml.SequenceNode:
+		key := sequenceKey(overlay)
+		if key == "" {
+			return overlay
+		}
+
+		for _, item := range overlay.Content {
+			j := sequenceIndex(base, key, scalarValue(item, key))
+			remove := false
+			if value := mappingValue(item, "remove"); value != nil {
+				value.Decode(&remove)
+			}
+
+			switch {
+			case remove:
+				if j != -1 {
+					base.Content = append(base.Content[:j], base.Content[j+1:]...)
+				}
+			case j != -1:
+				base.Content[j] = Merge(base.Content[j], item)
+			default:
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+	}
+
+	return overlay
+}
+
+// The field identifying the items of a list of views or actions, empty for
+// any other list
+func sequenceKey(node *yaml.Node) string {
+	for _, key := range []string{"view", "action", "profile"} {
+		keyed := len(node.Content) > 0
+		for _, item := range node.Content {
+			if scalarValue(item, key) == "" {
+				keyed = false
+				break
+			}
+		}
+		if keyed {
+			return key
+		}
+	}
+	return ""
+}
+
+func sequenceIndex(node *yaml.Node, key, value string) int {
+	for i, item := range node.Content {
+		if scalarValue(item, key) == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		overlay string
+		want    string
+	}{
+		{
+			"empty overlay",
+			"a: 1\n",
+			"",
+			"a: 1\n",
+		},
+		{
+			"mappings key by key",
+			"azcli:\n  path: az\n  output: json\n",
+			"azcli:\n  output: table\n  timeout: 10m\n",
+			"azcli:\n  path: az\n  output: table\n  timeout: 10m\n",
+		},
+		{
+			"plain lists replaced",
+			"args: [--only-show-errors]\n",
+			"args: [--debug]\n",
+			"args: [--debug]\n",
+		},
+		{
+			"views and actions by name",
+			"views:\n  - view: A\n    actions:\n      - action: Open\n        key: Enter\n      - action: Close\n        key: Esc\n  - view: B\n    actions:\n      - action: Open\n        key: o\n",
+			"views:\n  - view: A\n    actions:\n      - action: Close\n        key: q\n      - action: Refresh\n        key: R\n",
+			"views:\n  - view: A\n    actions:\n      - action: Open\n        key: Enter\n      - action: Close\n        key: q\n      - action: Refresh\n        key: R\n  - view: B\n    actions:\n      - action: Open\n        key: o\n",
+		},
+		{
+			"remove",
+			"views:\n  - view: A\n    actions:\n      - action: Open\n        key: Enter\n      - action: Close\n        key: Esc\n",
+			"views:\n  - view: A\n    actions:\n      - action: Open\n        remove: true\n      - action: Missing\n        remove: true\n",
+			"views:\n  - view: A\n    actions:\n      - action: Close\n        key: Esc\n",
+		},
+		{
+			"remove a view",
+			"views:\n  - view: A\n  - view: B\n",
+			"views:\n  - view: A\n    remove: true\n",
+			"views:\n  - view: B\n",
+		},
+		{
+			"profiles by name",
+			"profiles:\n  - profile: prod\n    tenant: contoso\n",
+			"profiles:\n  - profile: prod\n    credential: azurecli\n  - profile: dev\n",
+			"profiles:\n  - profile: prod\n    tenant: contoso\n    credential: azurecli\n  - profile: dev\n",
+		},
+		{
+			"different kinds",
+			"theme: {}\n",
+			"theme: dark\n",
+			"theme: dark\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var base, overlay yaml.Node
+		if err := yaml.Unmarshal([]byte(tt.base), &base); err != nil {
+			t.Fatal(err)
+		}
+		if err := yaml.Unmarshal([]byte(tt.overlay), &overlay); err != nil {
+			t.Fatal(err)
+		}
+
+		var got, want interface{}
+		if err := Merge(&base, &overlay).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: merged %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestLoadMergedDefaults(t *testing.T) {
+	config, err := LoadMerged([]byte("keyTimeout: 2s\nviews:\n  - view: AppLayout\n    actions:\n      - action: Quit\n        remove: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.KeyTimeout.String() != "2s" {
+		t.Errorf("key timeout %v", config.KeyTimeout)
+	}
+	if config.AzCLI.MaxConcurrentCommands == 0 {
+		t.Error("default azcli settings lost")
+	}
+	for _, view := range config.Views {
+		for _, action := range view.Actions {
+			if view.Name == AppLayoutView && action.Action == "Quit" {
+				t.Error("removed Quit binding still configured")
+			}
+		}
+	}
+	if len(config.Views) < 2 {
+		t.Errorf("default views lost, %v left", len(config.Views))
+	}
+}
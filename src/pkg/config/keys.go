@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// How long to wait for the next key of a key sequence when keyTimeout isn't
+// configured
+const DefaultKeyTimeout = time.Second
+
+// KeyStroke is a single key press with its modifiers. Characters are stored
+// as runes, with the shift state folded into the rune, and Ctrl
+// combinations of characters as the lower case rune with ModCtrl and, if
+// pressed, ModShift.
+type KeyStroke struct {
+	Mod  tcell.ModMask
+	Key  tcell.Key
+	Rune rune
+}
+
+// KeySequence is a key binding, one or more key strokes pressed in order.
+type KeySequence []KeyStroke
+
+// Special key names mapped to keys, lower case. Names of Ctrl combinations
+// are left out, those are written with a Ctrl modifier.
+var keyNames = func() map[string]tcell.Key {
+	names := map[string]tcell.Key{}
+	for key, name := range tcell.KeyNames {
+		if !strings.HasPrefix(name, "Ctrl-") {
+			names[strings.ToLower(name)] = key
+		}
+	}
+	return names
+}()
+
+// Names for characters that can't be written as themselves in a key binding
+var runeNames = map[string]rune{
+	"space": ' ',
+	"plus":  '+',
+}
+
+var modifierNames = map[string]tcell.ModMask{
+	"ctrl":    tcell.ModCtrl,
+	"control": tcell.ModCtrl,
+	"alt":     tcell.ModAlt,
+	"meta":    tcell.ModMeta,
+	"shift":   tcell.ModShift,
+}
+
+// ParseKeySequence parses a key binding. A binding is one or more key
+// strokes separated by spaces, e.g. "g g" or "Ctrl+W l". A key stroke is a
+// single character or a key name, e.g. Enter, Esc, F5, PgDn, Space or Plus,
+// optionally preceded by modifiers joined with "+", e.g. Ctrl+Shift+R or
+// Alt+Left. Key and modifier names are case insensitive.
+func ParseKeySequence(s string) (KeySequence, error) {
+	// A lone space is the space key rather than an empty binding
+	if s == " " {
+		return KeySequence{{Key: tcell.KeyRune, Rune: ' '}}, nil
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key binding")
+	}
+
+	sequence := make(KeySequence, 0, len(fields))
+	for _, field := range fields {
+		stroke, err := parseKeyStroke(field)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, stroke)
+	}
+
+	return sequence, nil
+}
+
+func parseKeyStroke(s string) (KeyStroke, error) {
+	if r := []rune(s); len(r) == 1 {
+		return normalizeRune(0, r[0]), nil
+	}
+
+	parts := strings.Split(s, "+")
+	// A trailing "+" is the plus key, e.g. Ctrl++
+	if strings.HasSuffix(s, "++") {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+
+	base := parts[len(parts)-1]
+	if base == "" {
+		return KeyStroke{}, fmt.Errorf("key %q has no key after its modifiers", s)
+	}
+
+	var mod tcell.ModMask
+	for _, name := range parts[:len(parts)-1] {
+		m, ok := modifierNames[strings.ToLower(name)]
+		if !ok {
+			return KeyStroke{}, fmt.Errorf("unknown modifier %q in key %q, expected Ctrl, Alt, Meta or Shift", name, s)
+		}
+		mod |= m
+	}
+
+	if r := []rune(base); len(r) == 1 {
+		return normalizeRune(mod, r[0]), nil
+	}
+	if r, ok := runeNames[strings.ToLower(base)]; ok {
+		return normalizeRune(mod, r), nil
+	}
+
+	key, ok := keyNames[strings.ToLower(base)]
+	if !ok {
+		return KeyStroke{}, fmt.Errorf("unknown key %q in %q, expected a single character or a key name such as Enter, Esc, F1, PgDn or Space", base, s)
+	}
+	if key == tcell.KeyTab && mod&tcell.ModShift != 0 {
+		key, mod = tcell.KeyBacktab, mod&^tcell.ModShift
+	}
+
+	return KeyStroke{Mod: mod, Key: key}, nil
+}
+
+// Shift is folded into the character, except with Ctrl where terminals that
+// report it at all report the character unshifted.
+func normalizeRune(mod tcell.ModMask, r rune) KeyStroke {
+	if mod&tcell.ModCtrl != 0 {
+		r = unicode.ToLower(r)
+	} else if mod&tcell.ModShift != 0 {
+		r = unicode.ToUpper(r)
+		mod &^= tcell.ModShift
+	}
+
+	return KeyStroke{Mod: mod, Key: tcell.KeyRune, Rune: r}
+}
+
+// KeyStrokeFromEvent returns the key stroke of a key event, in the form
+// ParseKeySequence produces.
+func KeyStrokeFromEvent(event *tcell.EventKey) KeyStroke {
+	key, mod := event.Key(), event.Modifiers()
+	if key == tcell.KeyRune {
+		return normalizeRune(mod, event.Rune())
+	}
+
+	// Terminals report Ctrl combinations of characters as control codes
+	if name := tcell.KeyNames[key]; strings.HasPrefix(name, "Ctrl-") {
+		base := strings.TrimPrefix(name, "Ctrl-")
+		if r, ok := runeNames[strings.ToLower(base)]; ok {
+			return normalizeRune(mod|tcell.ModCtrl, r)
+		}
+		return normalizeRune(mod|tcell.ModCtrl, []rune(base)[0])
+	}
+
+	return KeyStroke{Mod: mod, Key: key}
+}
+
+// String returns the key stroke in the canonical form of the binding
+// grammar, e.g. "Ctrl+Alt+x", "G", "Space" or "Shift+Left".
+func (k KeyStroke) String() string {
+	s := ""
+	for _, m := range []struct {
+		mod  tcell.ModMask
+		name string
+	}{{tcell.ModCtrl, "Ctrl"}, {tcell.ModAlt, "Alt"}, {tcell.ModMeta, "Meta"}, {tcell.ModShift, "Shift"}} {
+		if k.Mod&m.mod != 0 {
+			s += m.name + "+"
+		}
+	}
+
+	switch {
+	case k.Key != tcell.KeyRune:
+		if name, ok := tcell.KeyNames[k.Key]; ok {
+			return s + name
+		}
+		return s + fmt.Sprintf("Key[%d]", k.Key)
+	case k.Rune == ' ':
+		return s + "Space"
+	case k.Rune == '+':
+		return s + "Plus"
+	}
+
+	return s + string(k.Rune)
+}
+
+func (s KeySequence) String() string {
+	strokes := make([]string, 0, len(s))
+	for _, stroke := range s {
+		strokes = append(strokes, stroke.String())
+	}
+	return strings.Join(strokes, " ")
+}
+
+// HasPrefix reports whether the sequence starts with prefix.
+func (s KeySequence) HasPrefix(prefix KeySequence) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// KeyTimeout returns how long to wait for the next key of a key sequence.
+func KeyTimeout() time.Duration {
+	if Current().KeyTimeout > 0 {
+		return Current().KeyTimeout
+	}
+	return DefaultKeyTimeout
+}
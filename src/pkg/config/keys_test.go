@@ -0,0 +1,120 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKeySequence(t *testing.T) {
+	tests := []struct {
+		binding string
+		// Canonical form, empty when the binding doesn't parse
+		want string
+	}{
+		{"a", "a"},
+		{"G", "G"},
+		{"Shift+g", "G"},
+		{" ", "Space"},
+		{"space", "Space"},
+		{"Plus", "Plus"},
+		{"Ctrl++", "Ctrl+Plus"},
+		{"ctrl+A", "Ctrl+a"},
+		{"Ctrl+Shift+R", "Ctrl+Shift+r"},
+		{"control+w", "Ctrl+w"},
+		{"Alt+Left", "Alt+Left"},
+		{"Enter", "Enter"},
+		{"esc", "Esc"},
+		{"F5", "F5"},
+		{"PgDn", "PgDn"},
+		{"Shift+Tab", "Backtab"},
+		{"g g", "g g"},
+		{"Ctrl+W  l", "Ctrl+w l"},
+		{"!", "!"},
+		{"", ""},
+		{"   ", ""},
+		{"Hyper+q", ""},
+		{"Ctrl+", ""},
+		{"NoSuchKey", ""},
+		{"g NoSuchKey", ""},
+	}
+
+	for _, tt := range tests {
+		sequence, err := ParseKeySequence(tt.binding)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParseKeySequence(%q) = %v, want an error", tt.binding, sequence)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKeySequence(%q): %v", tt.binding, err)
+			continue
+		}
+		if got := sequence.String(); got != tt.want {
+			t.Errorf("ParseKeySequence(%q) = %v, want %v", tt.binding, got, tt.want)
+		}
+
+		// The canonical form parses to the same sequence
+		again, err := ParseKeySequence(sequence.String())
+		if err != nil || again.String() != sequence.String() {
+			t.Errorf("ParseKeySequence(%q) = %v, %v, want %v", sequence.String(), again, err, sequence)
+		}
+	}
+}
+
+func TestKeyStrokeFromEvent(t *testing.T) {
+	tests := []struct {
+		event   *tcell.EventKey
+		binding string
+	}{
+		{tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone), "a"},
+		{tcell.NewEventKey(tcell.KeyRune, 'G', tcell.ModShift), "G"},
+		{tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone), "Space"},
+		{tcell.NewEventKey(tcell.KeyCtrlA, 0, tcell.ModCtrl), "Ctrl+A"},
+		{tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModCtrl|tcell.ModShift), "Ctrl+Shift+R"},
+		{tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt), "Alt+x"},
+		{tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), "Enter"},
+		{tcell.NewEventKey(tcell.KeyF5, 0, tcell.ModNone), "F5"},
+		{tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone), "Shift+Tab"},
+	}
+
+	for _, tt := range tests {
+		want, err := ParseKeySequence(tt.binding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := KeyStrokeFromEvent(tt.event); got != want[0] {
+			t.Errorf("KeyStrokeFromEvent(%v) = %v, want %v", tt.event.Name(), got, want[0])
+		}
+	}
+}
+
+func TestKeySequenceHasPrefix(t *testing.T) {
+	tests := []struct {
+		sequence string
+		prefix   string
+		want     bool
+	}{
+		{"g g", "g", true},
+		{"g g", "g g", true},
+		{"g", "g g", false},
+		{"g g", "G", false},
+		{"Ctrl+W l", "Ctrl+w", true},
+		{"Ctrl+W l", "w", false},
+	}
+
+	for _, tt := range tests {
+		sequence, err := ParseKeySequence(tt.sequence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefix, err := ParseKeySequence(tt.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sequence.HasPrefix(prefix); got != tt.want {
+			t.Errorf("%q.HasPrefix(%q) = %v, want %v", tt.sequence, tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Profile is a tenant, cloud and credential to work with, and the scope to
+// start from. Profiles are selected with --profile or the profile switcher.
+type Profile struct {
+	Name string `yaml:"profile"`
+	// Tenant ID or domain to sign in to, the credential's own default when
+	// empty
+	Tenant string `yaml:"tenant,omitempty"`
+	// One of Clouds, AzurePublic when empty
+	Cloud string `yaml:"cloud,omitempty"`
+	// Endpoints of a Custom cloud, such as Azure Stack Hub
+	Endpoints Endpoints `yaml:"endpoints,omitempty"`
+	// One of Credentials, default when empty
+	Credential string `yaml:"credential,omitempty"`
+	// Application (client) ID of a service principal or workload identity,
+	// of a user-assigned managed identity, or of the application signing
+	// users in with devicecode and browser
+	ClientID string `yaml:"clientID,omitempty"`
+	// Secret of a service principal. Values are expanded, so it can be kept
+	// in the environment, e.g. "$AZURE_CLIENT_SECRET".
+	ClientSecret string `yaml:"clientSecret,omitempty"`
+	// PEM or PKCS#12 certificate of a service principal, with its private key
+	Certificate string `yaml:"certificate,omitempty"`
+	// Password of the certificate, expanded like clientSecret
+	CertificatePassword string `yaml:"certificatePassword,omitempty"`
+	// Service account token of a workload identity,
+	// $AZURE_FEDERATED_TOKEN_FILE when empty
+	TokenFile string `yaml:"tokenFile,omitempty"`
+	// Only subscriptions whose name or ID contains one of these are listed.
+	// Every subscription is listed when empty.
+	Subscriptions []string `yaml:"subscriptions,omitempty"`
+	// Resource group selected when the resource groups of a subscription are
+	// listed
+	ResourceGroup string `yaml:"resourceGroup,omitempty"`
+	// Variables added to the environment of az on top of azcli.env, e.g.
+	// AZURE_CONFIG_DIR to keep the profile's az login separate. They apply to
+	// the az login the azurecli and default credentials get tokens from too.
+	Env map[string]string `yaml:"env,omitempty"`
+}
+
+// Endpoints locate a cloud that isn't built in.
+type Endpoints struct {
+	// Microsoft Entra ID or AD FS authority, e.g.
+	// https://login.microsoftonline.com/
+	Authority string `yaml:"authority,omitempty"`
+	// Azure Resource Manager, e.g.
+	// https://management.local.azurestack.external
+	ResourceManager string `yaml:"resourceManager,omitempty"`
+	// Audience of Resource Manager tokens, resourceManager when empty
+	Audience string `yaml:"audience,omitempty"`
+	// Portal links are opened against, e.g.
+	// https://portal.local.azurestack.external
+	Portal string `yaml:"portal,omitempty"`
+}
+
+// CustomCloud is the cloud of profiles that set their own endpoints
+const CustomCloud = "Custom"
+
+// Clouds are the names of the clouds a profile can use.
+var Clouds = []string{"AzurePublic", "AzureGovernment", "AzureChina", CustomCloud}
+
+// Credentials are the credential types a profile can use. default tries
+// environment variables, workload and managed identity and the az login in
+// turn.
+var Credentials = []string{"default", "azurecli", "devicecode", "browser", "serviceprincipal", "workloadidentity", "managedidentity"}
+
+var (
+	// Guards profileName and credentialOverride, set while views read the
+	// active profile in the background
+	profileMu sync.RWMutex
+	// Selected with SetProfile, the configured DefaultProfile is used when empty
+	profileName string
+	// Selected with SetCredential, overrides the credential of every profile
+	credentialOverride string
+)
+
+// ActiveProfile returns the profile in use. Without profiles it is an empty
+// profile: the default credential in the public cloud.
+func ActiveProfile() Profile {
+	profileMu.RLock()
+	defer profileMu.RUnlock()
+
+	config := Current()
+	name := profileName
+	if name == "" {
+		name = config.DefaultProfile
+	}
+
+	profile, _ := findProfile(name, config.Profiles)
+	if credentialOverride != "" {
+		profile.Credential = credentialOverride
+	}
+	return profile
+}
+
+// SetCredential makes every profile use the credential type credential,
+// e.g. for a session started with --credential.
+func SetCredential(credential string) error {
+	if err := ValidateCredential(credential); err != nil {
+		return err
+	}
+
+	profileMu.Lock()
+	defer profileMu.Unlock()
+
+	credentialOverride = strings.ToLower(credential)
+	return nil
+}
+
+// SetProfile makes the profile name the active profile.
+func SetProfile(name string) error {
+	if _, ok := findProfile(name, Current().Profiles); !ok {
+		if len(Current().Profiles) == 0 {
+			return fmt.Errorf("unknown profile %q, no profiles are configured", name)
+		}
+		return fmt.Errorf("unknown profile %q, expected one of %v", name, strings.Join(ProfileNames(), ", "))
+	}
+
+	profileMu.Lock()
+	defer profileMu.Unlock()
+
+	profileName = name
+	return nil
+}
+
+// ProfileNames returns the names of the configured profiles, in file order.
+func ProfileNames() []string {
+	return profileNames(Current().Profiles)
+}
+
+func profileNames(profiles []Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+func findProfile(name string, profiles []Profile) (Profile, bool) {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// MatchesSubscription reports whether the subscription with the given name
+// and ID is listed in the profile.
+func (p Profile) MatchesSubscription(name, id string) bool {
+	if len(p.Subscriptions) == 0 {
+		return true
+	}
+
+	for _, filter := range p.Subscriptions {
+		filter = strings.ToLower(filter)
+		if strings.Contains(strings.ToLower(name), filter) || strings.Contains(strings.ToLower(id), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateCloud checks that cloud is one of Clouds, ignoring case.
+func ValidateCloud(cloud string) error {
+	for _, name := range Clouds {
+		if strings.EqualFold(cloud, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown cloud %q, expected one of %v", cloud, strings.Join(Clouds, ", "))
+}
+
+// ValidateEndpoints checks that a profile of the Custom cloud has endpoints,
+// and that other profiles don't.
+func (p Profile) ValidateEndpoints() error {
+	custom := strings.EqualFold(p.Cloud, CustomCloud)
+	switch {
+	case custom && (p.Endpoints.Authority == "" || p.Endpoints.ResourceManager == ""):
+		return fmt.Errorf("cloud %v needs the authority and resourceManager endpoints", CustomCloud)
+	case !custom && p.Endpoints != (Endpoints{}):
+		return fmt.Errorf("endpoints are only used with cloud %v", CustomCloud)
+	}
+	return nil
+}
+
+// ValidateCredentialSettings checks that the profile has the settings its
+// credential type needs.
+func (p Profile) ValidateCredentialSettings() error {
+	switch strings.ToLower(p.Credential) {
+	case "serviceprincipal":
+		switch {
+		case p.Tenant == "" || p.ClientID == "":
+			return fmt.Errorf("credential serviceprincipal needs tenant and clientID")
+		case (p.ClientSecret == "") == (p.Certificate == ""):
+			return fmt.Errorf("credential serviceprincipal needs either clientSecret or certificate")
+		}
+	default:
+		if p.ClientSecret != "" || p.Certificate != "" || p.CertificatePassword != "" {
+			return fmt.Errorf("clientSecret and certificate are only used with credential serviceprincipal")
+		}
+	}
+	return nil
+}
+
+// ValidateCredential checks that credential is one of Credentials.
+func ValidateCredential(credential string) error {
+	if contains(Credentials, strings.ToLower(credential)) {
+		return nil
+	}
+	return fmt.Errorf("unknown credential %q, expected one of %v", credential, strings.Join(Credentials, ", "))
+}
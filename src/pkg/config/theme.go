@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Theme sets the colors of the interface. A color is a color name such as
+// "navy" or "darkgreen", "#rrggbb" or "default" for the terminal's own
+// color. Colors left empty are taken from the built-in theme Name.
+type Theme struct {
+	// Built-in theme the colors below override, see Themes. Defaults to
+	// dark, or no-color when $NO_COLOR is set.
+	Name       string `yaml:"name,omitempty"`
+	Background string `yaml:"background,omitempty"`
+	Text       string `yaml:"text,omitempty"`
+	// Second line of list items, e.g. the location of a virtual machine
+	SecondaryText string `yaml:"secondaryText,omitempty"`
+	// Labels and the keys of JSON trees
+	Label string `yaml:"label,omitempty"`
+	// Background of input fields, buttons and dialogs
+	Input         string `yaml:"input,omitempty"`
+	Border        string `yaml:"border,omitempty"`
+	Title         string `yaml:"title,omitempty"`
+	Selection     string `yaml:"selection,omitempty"`
+	SelectionText string `yaml:"selectionText,omitempty"`
+	// Status and action bars
+	Status  string `yaml:"status,omitempty"`
+	Error   string `yaml:"error,omitempty"`
+	Warning string `yaml:"warning,omitempty"`
+	Success string `yaml:"success,omitempty"`
+	// Colors of virtual machine power states, e.g. running or deallocated
+	PowerStates map[string]string `yaml:"powerStates,omitempty"`
+}
+
+const (
+	DefaultTheme = "dark"
+	// Used instead of DefaultTheme when $NO_COLOR is set
+	NoColorTheme = "no-color"
+)
+
+// Themes are the built-in themes.
+var Themes = map[string]Theme{
+	"dark": {
+		Background:    "black",
+		Text:          "white",
+		SecondaryText: "green",
+		Label:         "yellow",
+		Input:         "blue",
+		Border:        "white",
+		Title:         "white",
+		Selection:     "white",
+		SelectionText: "black",
+		Status:        "white",
+		Error:         "red",
+		Warning:       "yellow",
+		Success:       "green",
+		PowerStates: map[string]string{
+			"running":      "green",
+			"starting":     "yellow",
+			"stopping":     "yellow",
+			"deallocating": "yellow",
+			"stopped":      "red",
+			"deallocated":  "gray",
+			"unknown":      "default",
+		},
+	},
+	"light": {
+		Background:    "white",
+		Text:          "black",
+		SecondaryText: "darkgreen",
+		Label:         "navy",
+		Input:         "lightgray",
+		Border:        "gray",
+		Title:         "black",
+		Selection:     "navy",
+		SelectionText: "white",
+		Status:        "black",
+		Error:         "darkred",
+		Warning:       "darkorange",
+		Success:       "darkgreen",
+		PowerStates: map[string]string{
+			"running":      "darkgreen",
+			"starting":     "darkorange",
+			"stopping":     "darkorange",
+			"deallocating": "darkorange",
+			"stopped":      "darkred",
+			"deallocated":  "gray",
+			"unknown":      "default",
+		},
+	},
+	"high-contrast": {
+		Background:    "black",
+		Text:          "white",
+		SecondaryText: "aqua",
+		Label:         "yellow",
+		Input:         "navy",
+		Border:        "white",
+		Title:         "yellow",
+		Selection:     "yellow",
+		SelectionText: "black",
+		Status:        "white",
+		Error:         "red",
+		Warning:       "yellow",
+		Success:       "lime",
+		PowerStates: map[string]string{
+			"running":      "lime",
+			"starting":     "yellow",
+			"stopping":     "yellow",
+			"deallocating": "yellow",
+			"stopped":      "red",
+			"deallocated":  "fuchsia",
+			"unknown":      "white",
+		},
+	},
+	// Every color is the terminal's own, selections are shown in reverse
+	NoColorTheme: {
+		Background:    "default",
+		Text:          "default",
+		SecondaryText: "default",
+		Label:         "default",
+		Input:         "default",
+		Border:        "default",
+		Title:         "default",
+		Selection:     "default",
+		SelectionText: "default",
+		Status:        "default",
+		Error:         "default",
+		Warning:       "default",
+		Success:       "default",
+		PowerStates:   map[string]string{},
+	},
+}
+
+// ThemeNames returns the names of the built-in themes, sorted.
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the built-in theme t is based on with the colors t sets
+// on top. An explicitly named theme wins over $NO_COLOR.
+func (t Theme) Resolve() (Theme, error) {
+	name := t.Name
+	if name == "" {
+		name = DefaultTheme
+		if os.Getenv("NO_COLOR") != "" {
+			name = NoColorTheme
+		}
+	}
+
+	base, ok := Themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q, expected one of %v", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	resolved := base
+	resolved.Name = name
+	for _, color := range []struct {
+		field    string
+		value    *string
+		override string
+	}{
+		{"background", &resolved.Background, t.Background},
+		{"text", &resolved.Text, t.Text},
+		{"secondaryText", &resolved.SecondaryText, t.SecondaryText},
+		{"label", &resolved.Label, t.Label},
+		{"input", &resolved.Input, t.Input},
+		{"border", &resolved.Border, t.Border},
+		{"title", &resolved.Title, t.Title},
+		{"selection", &resolved.Selection, t.Selection},
+		{"selectionText", &resolved.SelectionText, t.SelectionText},
+		{"status", &resolved.Status, t.Status},
+		{"error", &resolved.Error, t.Error},
+		{"warning", &resolved.Warning, t.Warning},
+		{"success", &resolved.Success, t.Success},
+	} {
+		if color.override == "" {
+			continue
+		}
+		if _, err := ParseColor(color.override); err != nil {
+			return Theme{}, fmt.Errorf("theme %v: %v", color.field, err)
+		}
+		*color.value = color.override
+	}
+
+	resolved.PowerStates = map[string]string{}
+	for state, color := range base.PowerStates {
+		resolved.PowerStates[state] = color
+	}
+	for state, color := range t.PowerStates {
+		if _, err := ParseColor(color); err != nil {
+			return Theme{}, fmt.Errorf("theme power state %v: %v", state, err)
+		}
+		resolved.PowerStates[strings.ToLower(state)] = color
+	}
+
+	return resolved, nil
+}
+
+// PowerStateColor returns the color of a virtual machine power state, e.g.
+// "running", or "default" for states the theme has no color for.
+func (t Theme) PowerStateColor(state string) string {
+	if color, ok := t.PowerStates[strings.ToLower(state)]; ok {
+		return color
+	}
+	return "default"
+}
+
+// ParseColor parses a theme color: a color name, "#rrggbb" or "default".
+func ParseColor(s string) (tcell.Color, error) {
+	name := strings.ToLower(s)
+	if name == "default" {
+		return tcell.ColorDefault, nil
+	}
+	if color, ok := tcell.ColorNames[name]; ok {
+		return color, nil
+	}
+	if strings.HasPrefix(name, "#") {
+		if color := tcell.GetColor(name); color != tcell.ColorDefault {
+			return color, nil
+		}
+	}
+
+	return tcell.ColorDefault, fmt.Errorf("unknown color %q, expected a color name such as navy, #rrggbb or default", s)
+}
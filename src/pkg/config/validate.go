@@ -0,0 +1,373 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Actions of this view are bound application wide, in front of the bindings
+// of the focused view.
+const AppLayoutView = "AppLayout"
+
+// Schema lists the views that can be configured and the actions each of them
+// supports.
+type Schema map[string][]string
+
+// Problem is a mistake found in a configuration file.
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) Error() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%v: %v", p.File, p.Message)
+	}
+	return fmt.Sprintf("%v:%v: %v", p.File, p.Line, p.Message)
+}
+
+// Problems is every mistake found in a configuration file, in file order.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	messages := make([]string, 0, len(p))
+	for _, problem := range p {
+		messages = append(messages, problem.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// yaml.v3 reports type errors as "line N: message"
+var yamlErrorLine = regexp.MustCompile(`^\s*(?:yaml: )?line (\d+): (.*)$`)
+
+// ValidateFile checks the user configuration file at path, and the result of
+// merging it on top of the defaults, against schema. Only the defaults are
+// checked when the file doesn't exist.
+func ValidateFile(path string, schema Schema) Problems {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Validate("(default configuration)", nil, schema)
+	}
+	if err != nil {
+		return Problems{{File: path, Message: err.Error()}}
+	}
+
+	return Validate(path, data, schema)
+}
+
+// Validate checks user configuration data read from file against schema:
+// unknown fields, unknown views and actions, malformed key names, profiles
+// and theme colors. Keys bound more than once are looked for in the result
+// of merging the data on top of the defaults.
+func Validate(file string, data []byte, schema Schema) Problems {
+	problems := Problems{}
+	add := func(line int, format string, args ...interface{}) {
+		problems = append(problems, Problem{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var config Config
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			for _, message := range typeErr.Errors {
+				line, message := splitYAMLError(message)
+				add(line, "%v", message)
+			}
+		} else {
+			// Syntax errors leave nothing else to check
+			line, message := splitYAMLError(err.Error())
+			add(line, "%v", message)
+			return problems
+		}
+	}
+
+	// Lines of the actions set in data, keyed by view and action name
+	lines := map[[2]string]int{}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err == nil && len(root.Content) > 0 {
+		if views := mappingValue(root.Content[0], "views"); views != nil && views.Kind == yaml.SequenceNode {
+			seenViews := map[string]int{}
+			for _, view := range views.Content {
+				name := scalarValue(view, "view")
+				if name == "" {
+					add(view.Line, "view has no name")
+					continue
+				}
+
+				supported, ok := schema[name]
+				if !ok {
+					add(view.Line, "unknown view %q, expected one of %v", name, strings.Join(sortedKeys(schema), ", "))
+					continue
+				}
+				if line, ok := seenViews[name]; ok {
+					add(view.Line, "view %v is already configured on line %v", name, line)
+				}
+				seenViews[name] = view.Line
+
+				for _, action := range actionNodes(view) {
+					actionName := scalarValue(action, "action")
+					key := scalarValue(action, "key")
+					lines[[2]string{name, actionName}] = action.Line
+
+					switch {
+					case actionName == "":
+						add(action.Line, "%v: action has no name", name)
+					case !contains(supported, actionName):
+						add(action.Line, "%v: unknown action %q, expected one of %v", name, actionName, strings.Join(sorted(supported), ", "))
+					}
+
+					if key == "" {
+						continue
+					}
+					if err := ValidateKey(key); err != nil {
+						add(action.Line, "%v: action %v: %v", name, actionName, err)
+					}
+				}
+			}
+		}
+
+		if profiles := mappingValue(root.Content[0], "profiles"); profiles != nil && profiles.Kind == yaml.SequenceNode {
+			seenProfiles := map[string]int{}
+			for _, profile := range profiles.Content {
+				name := scalarValue(profile, "profile")
+				if name == "" {
+					add(profile.Line, "profile has no name")
+					continue
+				}
+				if line, ok := seenProfiles[name]; ok {
+					add(profile.Line, "profile %v is already configured on line %v", name, line)
+				}
+				seenProfiles[name] = profile.Line
+
+				if cloud := mappingValue(profile, "cloud"); cloud != nil && cloud.Value != "" {
+					if err := ValidateCloud(cloud.Value); err != nil {
+						add(cloud.Line, "profile %v: %v", name, err)
+					}
+				}
+				var decoded Profile
+				if err := profile.Decode(&decoded); err == nil {
+					if err := decoded.ValidateEndpoints(); err != nil {
+						add(profile.Line, "profile %v: %v", name, err)
+					}
+					if err := decoded.ValidateCredentialSettings(); err != nil {
+						add(profile.Line, "profile %v: %v", name, err)
+					}
+				}
+				if credential := mappingValue(profile, "credential"); credential != nil && credential.Value != "" {
+					if err := ValidateCredential(credential.Value); err != nil {
+						add(credential.Line, "profile %v: %v", name, err)
+					}
+				}
+			}
+		}
+
+		if theme := mappingValue(root.Content[0], "theme"); theme != nil && theme.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(theme.Content); i += 2 {
+				key, value := theme.Content[i], theme.Content[i+1]
+				switch {
+				case key.Value == "name":
+					if _, ok := Themes[value.Value]; !ok && value.Value != "" {
+						add(value.Line, "unknown theme %q, expected one of %v", value.Value, strings.Join(ThemeNames(), ", "))
+					}
+				case key.Value == "powerStates" && value.Kind == yaml.MappingNode:
+					for j := 0; j+1 < len(value.Content); j += 2 {
+						if _, err := ParseColor(value.Content[j+1].Value); err != nil {
+							add(value.Content[j+1].Line, "theme power state %v: %v", value.Content[j].Value, err)
+						}
+					}
+				case value.Kind == yaml.ScalarNode && value.Value != "":
+					if _, err := ParseColor(value.Value); err != nil {
+						add(value.Line, "theme %v: %v", key.Value, err)
+					}
+				}
+			}
+		}
+	}
+
+	merged, err := LoadMerged(data)
+	if err != nil {
+		// Type errors are reported on their lines above
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			add(0, "%v", err)
+		}
+		return problems
+	}
+
+	if level := merged.Log.Level; level != "" && !contains(LogLevels, strings.ToLower(level)) {
+		add(lineOf(&root, "log", "level"), "unknown log level %q, expected one of %v", level, strings.Join(LogLevels, ", "))
+	}
+
+	if merged.DefaultProfile != "" {
+		if _, ok := findProfile(merged.DefaultProfile, merged.Profiles); !ok {
+			line := lineOf(&root, "defaultProfile")
+			if len(merged.Profiles) == 0 {
+				add(line, "unknown default profile %q, no profiles are configured", merged.DefaultProfile)
+			} else {
+				add(line, "unknown default profile %q, expected one of %v", merged.DefaultProfile, strings.Join(profileNames(merged.Profiles), ", "))
+			}
+		}
+	}
+
+	// Application wide bindings, to find view bindings they shadow
+	type binding struct {
+		action   string
+		sequence KeySequence
+	}
+	appBindings := []binding{}
+	for _, view := range merged.Views {
+		if view.Name == AppLayoutView {
+			for _, action := range view.Actions {
+				if sequence, err := ParseKeySequence(action.Key); err == nil {
+					appBindings = append(appBindings, binding{action.Action, sequence})
+				}
+			}
+		}
+	}
+
+	for _, view := range merged.Views {
+		bindings := []binding{}
+		for _, action := range view.Actions {
+			line := lines[[2]string{view.Name, action.Action}]
+			if action.Key == "" {
+				add(line, "%v: action %v has no key", view.Name, action.Action)
+				continue
+			}
+			sequence, err := ParseKeySequence(action.Key)
+			if err != nil {
+				// Reported above for keys set in data
+				continue
+			}
+
+			// Report conflicts on the line of whichever binding data set.
+			// A binding that is a prefix of another is fine, it fires once
+			// the key timeout passes without the longer one being typed.
+			for _, other := range bindings {
+				if sequence.String() == other.sequence.String() {
+					if line == 0 {
+						line = lines[[2]string{view.Name, other.action}]
+					}
+					add(line, "%v: key %q is bound to both %v and %v", view.Name, action.Key, other.action, action.Action)
+				}
+			}
+			if view.Name != AppLayoutView {
+				for _, other := range appBindings {
+					if sequence.HasPrefix(other.sequence) {
+						if line == 0 {
+							line = lines[[2]string{AppLayoutView, other.action}]
+						}
+						add(line, "%v: key %q of %v is shadowed by the application wide binding %q of %v", view.Name, action.Key, action.Action, other.sequence, other.action)
+					}
+				}
+			}
+			bindings = append(bindings, binding{action.Action, sequence})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+
+	return problems
+}
+
+// ValidateKey checks that key is a key binding ParseKeySequence accepts.
+func ValidateKey(key string) error {
+	_, err := ParseKeySequence(key)
+	return err
+}
+
+func splitYAMLError(message string) (int, string) {
+	match := yamlErrorLine.FindStringSubmatch(message)
+	if match == nil {
+		return 0, strings.TrimPrefix(message, "yaml: ")
+	}
+
+	line, _ := strconv.Atoi(match[1])
+	return line, match[2]
+}
+
+// lineOf returns the line of the value at path in the document root, or 0
+// when the document doesn't set it.
+func lineOf(root *yaml.Node, path ...string) int {
+	if len(root.Content) == 0 {
+		return 0
+	}
+
+	node := root.Content[0]
+	for _, key := range path {
+		if node = mappingValue(node, key); node == nil {
+			return 0
+		}
+	}
+	return node.Line
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarValue(node *yaml.Node, key string) string {
+	value := mappingValue(node, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return value.Value
+}
+
+func actionNodes(view *yaml.Node) []*yaml.Node {
+	actions := mappingValue(view, "actions")
+	if actions == nil || actions.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return actions.Content
+}
+
+func contains(values []string, value string) bool {
+	return indexOf(values, value) != -1
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func sorted(values []string) []string {
+	values = append([]string{}, values...)
+	sort.Strings(values)
+	return values
+}
+
+func sortedKeys(schema Schema) []string {
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
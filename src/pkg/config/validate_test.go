@@ -0,0 +1,114 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+var testSchema = Schema{
+	AppLayoutView:          {"Quit", "FocusInputField", "SpawnHistoryView", "ReloadConfig"},
+	"SubscriptionListView": {"SpawnResourceGroupListView", "RefreshView", "ToggleSelection"},
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// Expected problems as "line: part of the message", in order
+		want []string
+	}{
+		{"empty", "", nil},
+		{
+			"valid",
+			"views:\n  - view: SubscriptionListView\n    actions:\n      - action: RefreshView\n        key: Ctrl+U\n",
+			nil,
+		},
+		{
+			"syntax error",
+			"views:\n  - view: [\n",
+			[]string{"2: did not find expected node content"},
+		},
+		{
+			"unknown field",
+			"azcli:\n  path: az\n  pth: az\n",
+			[]string{"3: field pth not found"},
+		},
+		{
+			"wrong type",
+			"keyTimeout: 1s\nazcli:\n  maxConcurrentCommands: many\n",
+			[]string{"3: cannot unmarshal"},
+		},
+		{
+			"unknown view and action",
+			"views:\n  - view: NoSuchView\n  - view: SubscriptionListView\n    actions:\n      - action: Fly\n        key: f\n",
+			[]string{"2: unknown view \"NoSuchView\"", "5: SubscriptionListView: unknown action \"Fly\""},
+		},
+		{
+			"view configured twice",
+			"views:\n  - view: SubscriptionListView\n  - view: SubscriptionListView\n",
+			[]string{"3: view SubscriptionListView is already configured on line 2"},
+		},
+		{
+			"malformed key",
+			"views:\n  - view: SubscriptionListView\n    actions:\n      - action: RefreshView\n        key: Hyper+Q\n",
+			[]string{"4: SubscriptionListView: action RefreshView: "},
+		},
+		{
+			"key bound twice, reported on the line of the file",
+			"views:\n  - view: SubscriptionListView\n    actions:\n      - action: RefreshView\n        key: Enter\n",
+			[]string{"4: SubscriptionListView: key \"Enter\" is bound to both SpawnResourceGroupListView and RefreshView"},
+		},
+		{
+			"shadowed by an application wide binding",
+			"views:\n  - view: SubscriptionListView\n    actions:\n      - action: RefreshView\n        key: F9\n",
+			[]string{"4: SubscriptionListView: key \"F9\" of RefreshView is shadowed by the application wide binding"},
+		},
+		{
+			"unknown log level and default profile",
+			"log:\n  level: loud\ndefaultProfile: prod\n",
+			[]string{"2: unknown log level \"loud\"", "3: unknown default profile \"prod\", no profiles are configured"},
+		},
+		{
+			"profiles",
+			"profiles:\n  - profile: prod\n    cloud: Moon\n  - profile: prod\n  - tenant: contoso\n",
+			[]string{"3: profile prod: ", "4: profile prod is already configured on line 2", "5: profile has no name"},
+		},
+		{
+			"theme colors",
+			"theme:\n  name: neon\n  border: notacolor\n  powerStates:\n    running: alsonotacolor\n",
+			[]string{"2: unknown theme \"neon\"", "3: theme border: ", "5: theme power state running: "},
+		},
+	}
+
+	for _, tt := range tests {
+		problems := Validate("aztui.yaml", []byte(tt.data), testSchema)
+		if len(problems) != len(tt.want) {
+			t.Errorf("%v: %v problems, want %v:\n%v", tt.name, len(problems), len(tt.want), problems)
+			continue
+		}
+		for i, problem := range problems {
+			line, message, _ := strings.Cut(tt.want[i], ": ")
+			if got := problem.Error(); !strings.HasPrefix(got, "aztui.yaml:"+line+": ") || !strings.Contains(got, message) {
+				t.Errorf("%v: problem %q, want line %v and %q", tt.name, got, line, message)
+			}
+		}
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	// Every view and action of the defaults, so unknown ones would show up
+	schema := Schema{}
+	config, err := LoadMerged(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, view := range config.Views {
+		for _, action := range view.Actions {
+			schema[view.Name] = append(schema[view.Name], action.Action)
+		}
+	}
+
+	if problems := Validate("default.yaml", defaultConfig, schema); len(problems) != 0 {
+		t.Errorf("problems in the defaults:\n%v", problems)
+	}
+}
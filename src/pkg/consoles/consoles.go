@@ -1,8 +1,17 @@
 package consoles
 
 import (
-	"github.com/brendank310/azconsoles/pkg/azconsoles"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
 
+	armruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/brendank310/aztui/pkg/azclient"
+
+	"github.com/gobwas/ws"
 	"github.com/gobwas/ws/wsutil"
 	"github.com/rivo/tview"
 )
@@ -11,9 +20,10 @@ func StartSerialConsoleMonitor(subscriptionID string, resourceGroupName string,
 	t := tview.NewTextView()
 	t.SetTitle(vmName + " Console")
 	t.SetBorder(true)
-	conn, err := azconsoles.StartSerialConsole(subscriptionID, resourceGroupName, vmName)
+	conn, err := StartSerialConsole(subscriptionID, resourceGroupName, vmName)
 	if err != nil {
-		panic(err)
+		t.SetText(fmt.Sprintf("Failed to connect to the serial console: %v", err))
+		return t
 	}
 
 	go func() {
@@ -29,3 +39,80 @@ func StartSerialConsoleMonitor(subscriptionID string, resourceGroupName string,
 
 	return t
 }
+
+// StartSerialConsole connects to the serial console of a virtual machine in
+// the cloud of the active profile. It does what azconsoles.StartSerialConsole
+// does, which only knows the public cloud and the default credential.
+func StartSerialConsole(subscriptionID string, resourceGroupName string, vmName string) (net.Conn, error) {
+	cred, err := azclient.Credential()
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline, err := armruntime.NewPipeline("aztui", "", cred, runtime.PipelineOptions{}, azclient.ClientOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf(
+		"%v/subscriptions/%v/resourceGroups/%v/providers/Microsoft.Compute/virtualMachines/%v/providers/Microsoft.SerialConsole/serialPorts/0/connect",
+		azclient.ResourceManagerEndpoint(),
+		subscriptionID,
+		resourceGroupName,
+		vmName)
+
+	// Connecting doesn't change the virtual machine, so dry-run lets it
+	// through despite being a POST
+	ctx := azclient.ReadOnly(context.Background())
+	req, err := runtime.NewRequest(ctx, http.MethodPost, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	query := req.Raw().URL.Query()
+	query.Set("api-version", "2018-05-01")
+	req.Raw().URL.RawQuery = query.Encode()
+	req.Raw().Header.Set("Accept", "application/json")
+	if err := runtime.MarshalAsJSON(req, map[string]interface{}{
+		"properties": map[string]string{"state": "enabled"},
+	}); err != nil {
+		return nil, err
+	}
+
+	res, err := pipeline.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if !runtime.HasStatusCode(res, http.StatusOK) {
+		return nil, runtime.NewResponseError(res)
+	}
+
+	var connRes struct {
+		ConnectionString string `json:"connectionString"`
+	}
+	if err := runtime.UnmarshalAsJSON(res, &connRes); err != nil {
+		return nil, err
+	}
+	if connRes.ConnectionString == "" {
+		return nil, fmt.Errorf("empty connection string")
+	}
+
+	// The console authenticates with the token of the connect request
+	token := strings.TrimPrefix(res.Request.Header.Get("Authorization"), "Bearer ")
+
+	conn, _, _, err := ws.Dial(context.Background(), connRes.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := wsutil.ReadServerText(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := wsutil.WriteClientText(conn, []byte(token)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
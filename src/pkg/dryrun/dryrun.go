@@ -0,0 +1,134 @@
+package dryrun
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Mode decides what happens to az invocations and ARM requests that change
+// resources. Reads are never held back.
+type Mode int
+
+const (
+	// Off executes everything without asking
+	Off Mode = iota
+	// Preview shows what is about to be executed and waits for confirmation
+	Preview
+	// Strict shows what would be executed but never executes it
+	Strict
+)
+
+var (
+	// ErrDryRun is returned for requests that weren't executed because of
+	// strict dry-run mode.
+	ErrDryRun = errors.New("dry run, not executed")
+	// ErrDeclined is returned for requests that weren't confirmed in preview
+	// mode.
+	ErrDeclined = errors.New("not confirmed, not executed")
+)
+
+var (
+	mu          sync.Mutex
+	mode        Mode
+	confirmFunc func(Request) error
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Preview:
+		return "preview"
+	case Strict:
+		return "strict"
+	default:
+		return "off"
+	}
+}
+
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "off", "false":
+		return Off, nil
+	case "preview", "confirm":
+		return Preview, nil
+	case "strict", "true":
+		return Strict, nil
+	}
+	return Off, fmt.Errorf("unknown dry-run mode %q, expected off, preview or strict", s)
+}
+
+func CurrentMode() Mode {
+	mu.Lock()
+	defer mu.Unlock()
+	return mode
+}
+
+func SetMode(m Mode) {
+	mu.Lock()
+	defer mu.Unlock()
+	mode = m
+}
+
+// NextMode cycles Off -> Preview -> Strict -> Off and returns the new mode.
+func NextMode() Mode {
+	mu.Lock()
+	defer mu.Unlock()
+	mode = (mode + 1) % (Strict + 1)
+	return mode
+}
+
+// SetConfirmFunc sets the function asked to confirm requests in preview mode.
+// It is called on the goroutine issuing the request and blocks it until the
+// user decided, so requests must not be issued on the goroutine showing the
+// question. It returns nil when the request may be executed, ErrDeclined when
+// the user declined and another error when the user can't be asked.
+func SetConfirmFunc(f func(Request) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	confirmFunc = f
+}
+
+// Request is an az invocation or an ARM request about to be executed.
+type Request struct {
+	// az command line, empty for ARM requests
+	CommandLine string
+	Method      string
+	URL         string
+	Body        string
+}
+
+func (r Request) String() string {
+	if r.CommandLine != "" {
+		return "$ " + r.CommandLine
+	}
+
+	s := fmt.Sprintf("%v %v", r.Method, r.URL)
+	if r.Body != "" {
+		s += "\n\n" + r.Body
+	}
+	return s
+}
+
+// Check returns nil when req may be executed in the current mode. In preview
+// mode it blocks until the confirm function returns, without one nothing is
+// executed.
+func Check(req Request) error {
+	mu.Lock()
+	m, confirm := mode, confirmFunc
+	mu.Unlock()
+
+	switch m {
+	case Strict:
+		return fmt.Errorf("%w: %v", ErrDryRun, req)
+	case Preview:
+		if confirm == nil {
+			return fmt.Errorf("%w: %v", ErrDeclined, req)
+		}
+		if err := confirm(req); err != nil {
+			return fmt.Errorf("%w: %v", err, req)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package dryrun
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	errUnavailable := errors.New("can't ask")
+
+	tests := []struct {
+		name    string
+		mode    Mode
+		confirm func(Request) error
+		want    error
+	}{
+		{"off", Off, nil, nil},
+		{"strict", Strict, func(Request) error { return nil }, ErrDryRun},
+		{"preview without confirm func", Preview, nil, ErrDeclined},
+		{"preview confirmed", Preview, func(Request) error { return nil }, nil},
+		{"preview declined", Preview, func(Request) error { return ErrDeclined }, ErrDeclined},
+		{"preview can't ask", Preview, func(Request) error { return errUnavailable }, errUnavailable},
+	}
+
+	t.Cleanup(func() {
+		SetMode(Off)
+		SetConfirmFunc(nil)
+	})
+	for _, tt := range tests {
+		SetMode(tt.mode)
+		SetConfirmFunc(tt.confirm)
+
+		err := Check(Request{CommandLine: "az vm stop -n vm"})
+		if tt.want == nil && err != nil {
+			t.Errorf("%v: unexpected err %v", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%v: err %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Mode
+		err  bool
+	}{
+		{"", Off, false},
+		{"off", Off, false},
+		{"Preview", Preview, false},
+		{"confirm", Preview, false},
+		{"strict", Strict, false},
+		{"true", Strict, false},
+		{"maybe", Off, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseMode(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -1,22 +1,113 @@
 package logger
 
 import (
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-var Logger *log.Logger
+// Options configure where and what is logged.
+type Options struct {
+	// Log file, DefaultPath when empty
+	Path string
+	// Entries below this level are dropped
+	Level slog.Level
+	// The file is rotated once it grows past this many bytes, never when 0
+	MaxSize int64
+	// Rotated files kept next to the log file, path.1 being the newest
+	MaxBackups int
+}
+
+var (
+	level = new(slog.LevelVar)
+	// Only keeps entries for Recent until Init is called, packages shared
+	// with the command line tools log before, or without, it
+	logger = slog.New(recordingHandler{Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: level})})
+)
+
+// DefaultPath returns aztui.log in $XDG_STATE_HOME/aztui, or in
+// ~/.local/state/aztui when it is unset.
+func DefaultPath() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateDir, "aztui", "aztui.log"), nil
+}
 
-func InitLogger() error {
-	f, err := os.OpenFile("aztui.log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+// Init starts logging to the file options name.
+func Init(options Options) error {
+	path := options.Path
+	if path == "" {
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := openRotatingFile(path, options.MaxSize, options.MaxBackups)
 	if err != nil {
 		return err
 	}
-	Logger = log.New(f, "", log.LstdFlags)
-	Logger.Println("Starting log")
+
+	level.Set(options.Level)
+	logger = slog.New(recordingHandler{Handler: slog.NewTextHandler(file, &slog.HandlerOptions{Level: level})})
 	return nil
 }
 
-func Println(v ...interface{}) {
-	Logger.Println(v...)
+// Level returns the level entries are logged at and above.
+func Level() slog.Level {
+	return level.Level()
+}
+
+// ParseLevel parses a level name: debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", s)
+}
+
+// SetLevel drops entries below l from now on.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// Debug logs msg with key-value pairs args, e.g.
+// Debug("Key pressed", "key", "Ctrl+R").
+func Debug(msg string, args ...any) {
+	logger.Debug(msg, args...)
+}
+
+// Info logs msg at info level, see Debug.
+func Info(msg string, args ...any) {
+	logger.Info(msg, args...)
+}
+
+// Warn logs msg at warn level, see Debug.
+func Warn(msg string, args ...any) {
+	logger.Warn(msg, args...)
+}
+
+// Error logs msg at error level, see Debug.
+func Error(msg string, args ...any) {
+	logger.Error(msg, args...)
 }
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entries kept in memory for Recent
+const recentEntries = 1000
+
+// Entry is a logged message, as kept for Recent.
+type Entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// The key-value pairs logged with the message, formatted like in the file
+	Attrs string
+}
+
+func (e Entry) String() string {
+	s := fmt.Sprintf("%v %-5v %v", e.Time.Format("15:04:05.000"), e.Level, e.Message)
+	if e.Attrs != "" {
+		s += " " + e.Attrs
+	}
+	return s
+}
+
+var recent = struct {
+	sync.Mutex
+	// Ring of entries, next is where the next one goes
+	entries []Entry
+	next    int
+	full    bool
+	// Called with every new entry, see SetEntryFunc
+	entryFunc func(Entry)
+}{
+	entries: make([]Entry, recentEntries),
+}
+
+// Recent returns the last entries logged, oldest first.
+func Recent() []Entry {
+	recent.Lock()
+	defer recent.Unlock()
+
+	if !recent.full {
+		return append([]Entry{}, recent.entries[:recent.next]...)
+	}
+	return append(append([]Entry{}, recent.entries[recent.next:]...), recent.entries[:recent.next]...)
+}
+
+// SetEntryFunc sets a function called with every entry logged from now on,
+// on the goroutine logging it.
+func SetEntryFunc(f func(Entry)) {
+	recent.Lock()
+	defer recent.Unlock()
+	recent.entryFunc = f
+}
+
+func record(entry Entry) {
+	recent.Lock()
+	recent.entries[recent.next] = entry
+	recent.next = (recent.next + 1) % len(recent.entries)
+	if recent.next == 0 {
+		recent.full = true
+	}
+	f := recent.entryFunc
+	recent.Unlock()
+
+	if f != nil {
+		f(entry)
+	}
+}
+
+// recordingHandler keeps the entries its handler writes for Recent.
+type recordingHandler struct {
+	slog.Handler
+	// Attributes added with WithAttrs, already formatted
+	attrs string
+}
+
+func (h recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := []string{}
+	if h.attrs != "" {
+		attrs = append(attrs, h.attrs)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, formatAttr(a))
+		return true
+	})
+
+	record(Entry{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   strings.Join(attrs, " "),
+	})
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	formatted := []string{}
+	if h.attrs != "" {
+		formatted = append(formatted, h.attrs)
+	}
+	for _, a := range attrs {
+		formatted = append(formatted, formatAttr(a))
+	}
+	return recordingHandler{Handler: h.Handler.WithAttrs(attrs), attrs: strings.Join(formatted, " ")}
+}
+
+func (h recordingHandler) WithGroup(name string) slog.Handler {
+	return recordingHandler{Handler: h.Handler.WithGroup(name), attrs: h.attrs}
+}
+
+func formatAttr(a slog.Attr) string {
+	value := a.Value.Resolve().String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	return a.Key + "=" + value
+}
@@ -3,18 +3,22 @@ package resourceviews
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
 
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/azclient"
 	"github.com/brendank310/aztui/pkg/config"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
 )
 
 var aksClusterSelectItemFuncMap = map[string]func(*AKSClusterListView) tview.Primitive{
 	"SpawnAKSClusterDetailView": (*AKSClusterListView).SpawnAKSClusterDetailView,
+	"SpawnCommandListView":      (*AKSClusterListView).SpawnCommandListView,
+	"CopyPortalLink":            (*AKSClusterListView).CopyPortalLink,
+	"RefreshView":               (*AKSClusterListView).RefreshView,
 }
 
 type AKSClusterListView struct {
@@ -24,6 +28,10 @@ type AKSClusterListView struct {
 	SubscriptionID string
 	ResourceGroup  string
 	Parent         *AppLayout
+	// Listed instead of the resource group of SubscriptionID when set
+	Subscriptions []listItem
+	// The clusters with their Kubernetes versions, see listing
+	listing *listing
 }
 
 func NewAKSClusterListView(appLayout *AppLayout, subscriptionID string, resourceGroup string) *AKSClusterListView {
@@ -41,6 +49,10 @@ func NewAKSClusterListView(appLayout *AppLayout, subscriptionID string, resource
 	aks.SubscriptionID = subscriptionID
 	aks.ResourceGroup = resourceGroup
 	aks.Parent = appLayout
+	aks.listing = newListing(aks.Parent, aks.Name(), aks.List, title, listingKey(subscriptionID, resourceGroup, "Microsoft.ContainerService/managedClusters"), aks.fetch)
+	aks.listing.render = func() {
+		aks.listing.show(aks.listing.items, "(No AKS clusters in resource group)")
+	}
 
 	aks.List.SetFocusFunc(func() {
 		InitViewKeyBindings(&aks)
@@ -51,14 +63,33 @@ func NewAKSClusterListView(appLayout *AppLayout, subscriptionID string, resource
 	return &aks
 }
 
+// NewAllAKSClustersListView lists the clusters of every one of
+// subscriptions, with the subscription of each.
+func NewAllAKSClustersListView(appLayout *AppLayout, subscriptions []listItem) *AKSClusterListView {
+	aks := NewAKSClusterListView(appLayout, "", "")
+	appLayout.FocusedViewIndex = 1
+	title := fmt.Sprintf("AKS Clusters in %v subscriptions (F%v)", len(subscriptions), appLayout.FocusedViewIndex+1)
+
+	aks.List.Box.SetTitle(title)
+	// The subscription column is secondary text
+	aks.List.ShowSecondaryText(true)
+	aks.Subscriptions = subscriptions
+	aks.listing = newListing(aks.Parent, aks.Name(), aks.List, title, listingKey("", subscriptionScope(subscriptions), "Microsoft.ContainerService/managedClusters"), aks.fetch)
+	aks.listing.render = func() {
+		aks.listing.show(aks.listing.items, "(No AKS clusters in subscriptions)")
+	}
+
+	return aks
+}
+
 func (a *AKSClusterListView) UpdateActionBar(t *tview.TextView) {
 	actionBarText := ""
-	for _, view := range config.GConfig.Views {
+	for _, view := range config.Current().Views {
 		if view.Name == a.Name() {
 			for _, action := range view.Actions {
 				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
 			}
-			actionBarText = actionBarText[:len(actionBarText)-3] // Remove the last " | "
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
 			break
 		}
 	}
@@ -82,7 +113,7 @@ func (v *AKSClusterListView) CallAction(action string) (tview.Primitive, error)
 	if actionFunc, ok := aksClusterSelectItemFuncMap[action]; ok {
 		return actionFunc(v), nil
 	}
-	return nil, fmt.Errorf("no action for %s", action)
+	return v.listing.callAction(action)
 }
 
 func (v *AKSClusterListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
@@ -90,71 +121,152 @@ func (v *AKSClusterListView) AppendPrimitiveView(p tview.Primitive, takeFocus bo
 }
 
 func (v *AKSClusterListView) SpawnAKSClusterDetailView() tview.Primitive {
-	aksClusterName, _ := v.List.GetItemText(v.List.GetCurrentItem())
-	t := tview.NewForm()
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	item, ok := v.listing.selected()
+	if !ok {
+		return nil
+	}
+	aksClusterName := item.Name
+	subscriptionID, resourceGroup := v.scopeOf(item)
+	detail := NewResourceDetailView(v.Parent, aksClusterName+" Details", func() (interface{}, error) {
+		cred, err := azclient.Credential()
+		if err != nil {
+			return nil, err
+		}
+
+		// Create a client to interact with AKS
+		client, err := armcontainerservice.NewManagedClustersClient(subscriptionID, cred, azclient.ClientOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AKS client: %w", err)
+		}
+
+		aksCluster, err := client.Get(context.Background(), resourceGroup, aksClusterName, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get AKS cluster: %w", err)
+		}
+		return aksCluster.ManagedCluster, nil
+	})
+
+	return detail.Pages
+}
+
+// Update shows the clusters, cached ones first, and lists them again in the
+// background.
+func (v *AKSClusterListView) Update() error {
+	v.listing.load()
+	return nil
+}
+
+// RefreshView lists the clusters again.
+func (v *AKSClusterListView) RefreshView() tview.Primitive {
+	v.Update()
+	return nil
+}
+
+func (v *AKSClusterListView) fetch() ([]listItem, error) {
+	if len(v.Subscriptions) > 0 {
+		return fanOut(v.Subscriptions, func(subscriptionID string) ([]listItem, error) {
+			return fetchAKSClusters(subscriptionID, "")
+		})
+	}
+	return fetchAKSClusters(v.SubscriptionID, v.ResourceGroup)
+}
+
+// fetchAKSClusters lists the clusters of resourceGroup, or of the whole
+// subscription when it is empty.
+func fetchAKSClusters(subscriptionID, resourceGroup string) ([]listItem, error) {
+	cred, err := azclient.Credential()
 	if err != nil {
-		log.Fatalf("Failed to obtain a credential: %v", err)
+		return nil, fmt.Errorf("failed to obtain a credential: %v", err)
 	}
 
 	// Create a context
 	ctx := context.Background()
 
 	// Create a client to interact with AKS
-	client, err := armcontainerservice.NewManagedClustersClient(v.SubscriptionID, cred, nil)
+	client, err := armcontainerservice.NewManagedClustersClient(subscriptionID, cred, azclient.ClientOptions())
 	if err != nil {
-		log.Fatalf("failed to create AKS client: %v", err)
+		return nil, fmt.Errorf("failed to create AKS client: %v", err)
 	}
 
-	aksCluster, err := client.Get(ctx, v.ResourceGroup, aksClusterName, nil)
-	if err != nil {
-		log.Fatalf("Failed to get VM: %v", err)
+	clusters := []*armcontainerservice.ManagedCluster{}
+	if resourceGroup == "" {
+		clusterListPager := client.NewListPager(nil)
+		for clusterListPager.More() {
+			page, err := clusterListPager.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the next page of AKS clusters: %v", err)
+			}
+			clusters = append(clusters, page.Value...)
+		}
+	} else {
+		// List AKS clusters in the specified resource group
+		clusterListPager := client.NewListByResourceGroupPager(resourceGroup, nil)
+		for clusterListPager.More() {
+			page, err := clusterListPager.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the next page of AKS clusters: %v", err)
+			}
+			clusters = append(clusters, page.Value...)
+		}
 	}
 
-	t.SetTitle(aksClusterName + " Details")
-	t.AddInputField("AKS Cluster Name", *aksCluster.Name, 0, nil, nil).
-		AddInputField("Resource ID", *aksCluster.ID, 0, nil, nil).
-		AddInputField("Location", *aksCluster.Location, 0, nil, nil)
-	t.SetBorder(true)
+	items := []listItem{}
+	for _, cluster := range clusters {
+		version := ""
+		if cluster.Properties != nil && cluster.Properties.KubernetesVersion != nil {
+			version = *cluster.Properties.KubernetesVersion
+		}
+		items = append(items, listItem{Name: *cluster.Name, Secondary: version, ID: *cluster.ID})
+	}
 
-	return t
+	return items, nil
 }
 
-func (v *AKSClusterListView) Update() error {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		return fmt.Errorf("failed to obtain a credential: %v", err)
+// scopeOf returns the subscription and resource group of the cluster of
+// item.
+func (v *AKSClusterListView) scopeOf(item listItem) (string, string) {
+	if len(v.Subscriptions) == 0 {
+		return v.SubscriptionID, v.ResourceGroup
 	}
+	subscriptionID, resourceGroup, _ := item.scope()
+	return subscriptionID, resourceGroup
+}
 
-	v.List.Clear()
-	// Create a context
-	ctx := context.Background()
-
-	// Create a client to interact with AKS
-	client, err := armcontainerservice.NewManagedClustersClient(v.SubscriptionID, cred, nil)
-	if err != nil {
-		log.Fatalf("failed to create AKS client: %v", err)
+func (v *AKSClusterListView) SpawnCommandListView() tview.Primitive {
+	targets := v.targets("SpawnCommandListView")
+	if len(targets) == 0 {
+		return nil
 	}
+	v.Parent.RemoveViewsAfter(v.List)
+	cmdList := NewBulkCommandListView(v.Parent, targets)
 
-	// List AKS clusters in the specified resource group
-	clusterListPager := client.NewListByResourceGroupPager(v.ResourceGroup, nil)
+	return cmdList.List
+}
 
-	// Check if the pager is empty
-	if !clusterListPager.More() {
-		v.List.AddItem("(No AKS clusters in resource group)", "", 0, nil)
+// targets returns the clusters action acts on, see listing.targets.
+func (v *AKSClusterListView) targets(action string) []azcli.Target {
+	targets := []azcli.Target{}
+	for _, item := range v.listing.targets(action) {
+		targets = append(targets, v.target(item))
 	}
+	return targets
+}
 
-	// Iterate through the pager to fetch all AKS clusters
-	for clusterListPager.More() {
-		page, err := clusterListPager.NextPage(ctx)
-		if err != nil {
-			log.Fatalf("failed to get the next page of AKS clusters: %v", err)
-		}
+// target returns the cluster of item.
+func (v *AKSClusterListView) target(item listItem) azcli.Target {
+	subscriptionID, resourceGroup := v.scopeOf(item)
+	return azcli.Target{
+		SubscriptionID: subscriptionID,
+		ResourceGroup:  resourceGroup,
+		Type:           "Microsoft.ContainerService/managedClusters",
+		Name:           item.Name,
+	}
+}
 
-		// Loop through the AKS clusters and print their details
-		for _, cluster := range page.Value {
-			v.List.AddItem(*cluster.Name, *cluster.Properties.KubernetesVersion, 0, nil)
-		}
+// CopyPortalLink copies the link to the selected cluster in the portal.
+func (v *AKSClusterListView) CopyPortalLink() tview.Primitive {
+	if targets := v.targets("CopyPortalLink"); len(targets) > 0 {
+		v.Parent.CopyPortalLink(targets...)
 	}
 
 	return nil
@@ -0,0 +1,224 @@
+package resourceviews
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/rivo/tview"
+)
+
+const azCommandFormModal = "azCommandForm"
+
+// NewAzCommandForm builds a form with one field per parameter of command.
+// Fields are prefilled from values, keyed by the long form of the parameter,
+// and parameters listed in required are treated as required even when az's
+// help does not mark them as such. submit is called with the complete az
+// arguments once every required parameter has a value.
+func NewAzCommandForm(command *azcli.Command, values map[string]string, required []string, submit func(args []string), cancel func()) *tview.Form {
+	form := tview.NewForm()
+	title := "az " + command.Path()
+	form.SetTitle(title)
+	form.SetBorder(true)
+
+	isRequired := func(p azcli.Parameter) bool {
+		if p.Required {
+			return true
+		}
+		for _, r := range required {
+			for _, o := range p.Options {
+				if o == r {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	// Required parameters first, in the order az lists them
+	params := command.CommandParameters()
+	sort.SliceStable(params, func(i, j int) bool {
+		return isRequired(params[i]) && !isRequired(params[j])
+	})
+
+	for _, p := range params {
+		p := p
+		label := p.Name
+		if isRequired(p) {
+			label += " *"
+		}
+
+		if len(p.Choices) > 0 {
+			options := append([]string{""}, p.Choices...)
+			initial := 0
+			for i, option := range options {
+				if option == values[p.Name] {
+					initial = i
+				}
+			}
+			form.AddDropDown(label, options, initial, func(option string, _ int) {
+				values[p.Name] = option
+			})
+			continue
+		}
+
+		field := tview.NewInputField().
+			SetLabel(label).
+			SetText(values[p.Name]).
+			SetChangedFunc(func(text string) {
+				values[p.Name] = text
+			})
+		if p.Default != "" {
+			field.SetPlaceholder("default: " + p.Default)
+		} else if p.Type == azcli.ParameterTypeList {
+			field.SetPlaceholder("space-separated values")
+		}
+		form.AddFormItem(field)
+	}
+
+	form.AddButton("Run", func() {
+		missing := []string{}
+		for _, p := range params {
+			if isRequired(p) && strings.TrimSpace(values[p.Name]) == "" {
+				missing = append(missing, p.Name)
+			}
+		}
+
+		if len(missing) > 0 {
+			form.SetTitle(fmt.Sprintf("%v - %v", title, errorText("missing "+strings.Join(missing, ", "))))
+			return
+		}
+
+		submit(azcli.BuildArgs(*command, values))
+	})
+	form.AddButton("Cancel", cancel)
+	form.SetCancelFunc(cancel)
+
+	return form
+}
+
+// PromptAndRunAzCommand runs command with the values the caller already
+// knows, e.g. the resource group and name of the selected resource. When a
+// required parameter has no value, or az rejects the invocation because of a
+// missing argument, a modal form is shown to fill in the gaps first. With
+// confirm set the form is always shown. show is called with the output view
+// of every invocation before it starts.
+func PromptAndRunAzCommand(layout *AppLayout, command *azcli.Command, known map[string]string, confirm bool, show func(*CommandOutputView)) {
+	values := parameterValues(command, known)
+
+	var prompt func(required []string)
+	run := func(args []string) {
+		output := NewCommandOutputView(layout, azcli.WithDefaultOutput(args))
+		show(output)
+		output.Run(func(result azcli.Result) {
+			if result.ExitCode == 0 || result.Canceled {
+				return
+			}
+			if missing := azcli.MissingArguments(result.Stderr); len(missing) > 0 {
+				prompt(missing)
+			}
+		})
+	}
+
+	prompt = func(required []string) {
+		form := NewAzCommandForm(command, values, required, func(args []string) {
+			layout.HideModal(azCommandFormModal)
+			run(args)
+		}, func() {
+			layout.HideModal(azCommandFormModal)
+		})
+
+		showAzCommandForm(layout, form)
+	}
+
+	if confirm {
+		prompt(nil)
+		return
+	}
+
+	for _, p := range command.CommandParameters() {
+		if p.Required && values[p.Name] == "" {
+			prompt(nil)
+			return
+		}
+	}
+
+	run(azcli.BuildArgs(*command, values))
+}
+
+// PromptAndRunAzCommandOnTargets asks for the values to run command with
+// against several targets, known holding the values known for each. The
+// form is prefilled with the values of the first target. Parameters whose
+// known values differ between targets, such as the name, are set to each
+// target's own value whatever is entered for them. run is called with the
+// arguments for every target, in the order of known. Nothing is asked
+// without targets.
+func PromptAndRunAzCommandOnTargets(layout *AppLayout, command *azcli.Command, known []map[string]string, run func(args [][]string)) {
+	if len(known) == 0 {
+		return
+	}
+
+	targetValues := make([]map[string]string, len(known))
+	for i := range known {
+		targetValues[i] = parameterValues(command, known[i])
+	}
+
+	differing := map[string]bool{}
+	for _, values := range targetValues[1:] {
+		for _, p := range command.Parameters {
+			if values[p.Name] != targetValues[0][p.Name] {
+				differing[p.Name] = true
+			}
+		}
+	}
+
+	values := make(map[string]string)
+	for name, value := range targetValues[0] {
+		values[name] = value
+	}
+
+	form := NewAzCommandForm(command, values, nil, func([]string) {
+		layout.HideModal(azCommandFormModal)
+
+		args := [][]string{}
+		for _, target := range targetValues {
+			merged := make(map[string]string)
+			for name, value := range values {
+				merged[name] = value
+			}
+			for name := range differing {
+				merged[name] = target[name]
+			}
+			args = append(args, azcli.WithDefaultOutput(azcli.BuildArgs(*command, merged)))
+		}
+		run(args)
+	}, func() {
+		layout.HideModal(azCommandFormModal)
+	})
+	form.SetTitle(fmt.Sprintf("az %v (%v targets)", command.Path(), len(known)))
+	showAzCommandForm(layout, form)
+}
+
+// parameterValues returns the known values of the parameters of command,
+// keyed by parameter name rather than option.
+func parameterValues(command *azcli.Command, known map[string]string) map[string]string {
+	values := make(map[string]string)
+	for _, p := range command.Parameters {
+		for _, o := range p.Options {
+			if v, ok := known[o]; ok && v != "" {
+				values[p.Name] = v
+			}
+		}
+	}
+	return values
+}
+
+func showAzCommandForm(layout *AppLayout, form *tview.Form) {
+	height := 2*form.GetFormItemCount() + 5
+	if height > 30 {
+		height = 30
+	}
+	layout.HideModal(azCommandFormModal)
+	layout.ShowModal(azCommandFormModal, form, 100, height)
+}
@@ -0,0 +1,306 @@
+package resourceviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/dryrun"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var bulkRunFuncMap = map[string]func(*BulkRunView) tview.Primitive{
+	"ShowBulkRunOutput": (*BulkRunView).ShowBulkRunOutput,
+	"CancelBulkRun":     (*BulkRunView).CancelBulkRun,
+	"CloseBulkRunView":  (*BulkRunView).CloseBulkRunView,
+}
+
+// Why a command of a bulk run wasn't executed
+var errNotConfirmed = errors.New("not confirmed, not executed")
+
+// bulkRun is the command run against one of the targets of a BulkRunView.
+// It is only changed on the UI goroutine.
+type bulkRun struct {
+	Target azcli.Target
+	Args   []string
+	// Set once the command started, and once it exited
+	started bool
+	done    bool
+	result  azcli.Result
+	// Why the command didn't run or couldn't be started
+	err error
+}
+
+// BulkRunView runs an az command against several targets, at most
+// config.Current().AzCLI.MaxConcurrentCommands at a time, and lists how it went for each as
+// they finish.
+type BulkRunView struct {
+	List   *tview.List
+	Parent *AppLayout
+	// The az command, e.g. "vm stop"
+	Command string
+	runs    []*bulkRun
+	cancel  context.CancelFunc
+	output  tview.Primitive
+}
+
+// NewBulkRunView lists the targets command is run against, with the
+// arguments of each in args.
+func NewBulkRunView(layout *AppLayout, command string, targets []azcli.Target, args [][]string) *BulkRunView {
+	b := BulkRunView{
+		List:    tview.NewList(),
+		Parent:  layout,
+		Command: command,
+	}
+
+	b.List.SetBorder(true)
+	b.List.ShowSecondaryText(true)
+	for i, target := range targets {
+		b.runs = append(b.runs, &bulkRun{Target: target, Args: args[i]})
+		b.List.AddItem(tview.Escape(target.Name), "", 0, nil)
+	}
+
+	b.List.SetFocusFunc(func() {
+		InitViewKeyBindings(&b)
+		b.UpdateActionBar(b.Parent.ActionBar)
+	})
+	b.Update()
+
+	return &b
+}
+
+func (b *BulkRunView) UpdateActionBar(t *tview.TextView) {
+	actionBarText := ""
+	for _, view := range config.Current().Views {
+		if view.Name == b.Name() {
+			for _, action := range view.Actions {
+				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
+			}
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
+			break
+		}
+	}
+
+	t.SetText(actionBarText)
+}
+
+func (b *BulkRunView) Name() string {
+	return "BulkRunView"
+}
+
+func (b *BulkRunView) SetInputCapture(f func(event *tcell.EventKey) *tcell.EventKey) {
+	b.List.SetInputCapture(f)
+}
+
+func (b *BulkRunView) CustomInputHandler() func(event *tcell.EventKey) *tcell.EventKey {
+	return nil
+}
+
+func (b *BulkRunView) CallAction(action string) (tview.Primitive, error) {
+	if actionFunc, ok := bulkRunFuncMap[action]; ok {
+		return actionFunc(b), nil
+	}
+	return nil, fmt.Errorf("no action for %s", action)
+}
+
+func (b *BulkRunView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
+	b.Parent.AppendPrimitiveView(p, takeFocus, width)
+}
+
+// Update shows how far the command got for every target, and a summary in
+// the title.
+func (b *BulkRunView) Update() error {
+	done, failed, running := 0, 0, 0
+	for i, run := range b.runs {
+		status := "pending"
+		switch {
+		case run.done && run.err != nil:
+			status = errorText(tview.Escape(run.err.Error()))
+			failed++
+		case run.done && run.result.Canceled:
+			status = warningText("canceled")
+		case run.done && run.result.ExitCode != 0:
+			status = errorText(tview.Escape(fmt.Sprintf("exit %v: %v", run.result.ExitCode, firstLine(run.result.Stderr))))
+			failed++
+		case run.done:
+			status = successText(fmt.Sprintf("exit 0 after %v", run.result.Duration.Round(10*time.Millisecond)))
+		case errors.Is(run.err, context.Canceled):
+			status = warningText("canceled")
+		case run.err != nil:
+			status = warningText(tview.Escape(run.err.Error()))
+		case run.started:
+			status = "running"
+			running++
+		}
+		if run.done || run.err != nil {
+			done++
+		}
+
+		b.List.SetItemText(i, tview.Escape(run.Target.Name), tview.Escape(run.Target.ID())+" | "+status)
+	}
+
+	title := fmt.Sprintf("az %v: %v of %v done", b.Command, done, len(b.runs))
+	if running > 0 {
+		title += fmt.Sprintf(", %v running", running)
+	}
+	if failed > 0 {
+		title += ", " + errorText(fmt.Sprintf("%v failed", failed))
+	}
+	b.List.SetTitle(title)
+
+	return nil
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// Run starts the commands. Mutating commands are shown for confirmation all
+// at once first in preview dry-run mode, and not run in strict mode.
+func (b *BulkRunView) Run() {
+	if len(b.runs) == 0 || azcli.IsReadOnlyCommand(b.runs[0].Args) {
+		b.start()
+		return
+	}
+
+	notRun := func(err error) {
+		for _, run := range b.runs {
+			run.err = err
+		}
+		b.Update()
+	}
+
+	switch dryrun.CurrentMode() {
+	case dryrun.Strict:
+		notRun(dryrun.ErrDryRun)
+	case dryrun.Preview:
+		b.Parent.ShowPreview(dryrun.Request{CommandLine: b.commandLines()}, b.start, func() {
+			notRun(errNotConfirmed)
+		})
+	default:
+		b.start()
+	}
+}
+
+// commandLines lists the command lines of the first runs, for the preview.
+func (b *BulkRunView) commandLines() string {
+	const shown = 10
+
+	lines := []string{}
+	for i, run := range b.runs {
+		if i == shown {
+			lines = append(lines, fmt.Sprintf("... and %v more", len(b.runs)-shown))
+			break
+		}
+		lines = append(lines, azcli.ShellCommand(run.Args))
+	}
+	return strings.Join(lines, "\n$ ")
+}
+
+func (b *BulkRunView) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+
+	workers := config.Current().AzCLI.MaxConcurrentCommands
+	if workers <= 0 {
+		workers = 1
+	}
+
+	next := make(chan *bulkRun, len(b.runs))
+	for _, run := range b.runs {
+		next <- run
+	}
+	close(next)
+
+	for w := 0; w < workers && w < len(b.runs); w++ {
+		go func() {
+			for run := range next {
+				b.execute(ctx, run)
+			}
+		}()
+	}
+}
+
+// execute runs the command of run, off the UI goroutine.
+func (b *BulkRunView) execute(ctx context.Context, run *bulkRun) {
+	if ctx.Err() != nil {
+		queueUpdateDraw(func() {
+			run.err = ctx.Err()
+			b.Update()
+		})
+		return
+	}
+
+	queueUpdateDraw(func() {
+		run.started = true
+		b.Update()
+	})
+
+	var result azcli.Result
+	e, err := azcli.StartAzCommand(ctx, run.Args, nil, nil)
+	if err == nil {
+		result = e.Wait()
+		if err := azcli.AppendHistory(azcli.NewHistoryEntry(result, run.Target.ID())); err != nil {
+			logger.Warn("Failed to record command history", "err", err)
+		}
+	}
+	logger.Info("Bulk run command exited", "command", azcli.ShellCommand(run.Args), "exitCode", result.ExitCode, "err", err)
+
+	queueUpdateDraw(func() {
+		run.done = true
+		run.result = result
+		run.err = err
+		b.Update()
+	})
+}
+
+// ShowBulkRunOutput shows what the command printed for the selected target.
+func (b *BulkRunView) ShowBulkRunOutput() tview.Primitive {
+	current := b.List.GetCurrentItem()
+	if current < 0 || current >= len(b.runs) {
+		return nil
+	}
+	run := b.runs[current]
+
+	output := tview.NewTextView()
+	output.SetTitle(tview.Escape(run.Target.Name))
+	output.SetBorder(true)
+	output.SetScrollable(true)
+	output.SetDynamicColors(true)
+	fmt.Fprintf(output, "[::b]$ %v[::-]\n", tview.Escape(azcli.ShellCommand(run.Args)))
+	fmt.Fprint(output, tview.Escape(run.result.Stdout))
+	if run.result.Stderr != "" {
+		fmt.Fprint(output, errorText(tview.Escape(run.result.Stderr)))
+	}
+	if run.err != nil {
+		fmt.Fprintln(output, errorText(tview.Escape(run.err.Error())))
+	}
+	b.Parent.ReplaceView(b.output, output, true, 3)
+	b.output = output
+
+	return nil
+}
+
+// CancelBulkRun kills the running commands and skips the pending ones.
+func (b *BulkRunView) CancelBulkRun() tview.Primitive {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	return nil
+}
+
+func (b *BulkRunView) CloseBulkRunView() tview.Primitive {
+	if b.output != nil {
+		b.Parent.Layout.RemoveItem(b.output)
+	}
+	b.Parent.Layout.RemoveItem(b.List)
+	b.Parent.FocusView(0)
+	return nil
+}
@@ -0,0 +1,299 @@
+package resourceviews
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+)
+
+// CommandListView lists the az commands available for a resource, based on
+// the az command groups that manage its type. Subgroups can be browsed into,
+// and selecting a command runs it against the resource.
+type CommandListView struct {
+	List          *tview.List
+	StatusBarText string
+	ActionBarText string
+	Target        azcli.Target
+	Groups        []string
+	Parent        *AppLayout
+	// Every target commands are run against when there are several, the
+	// first of them is Target
+	Targets []azcli.Target
+	// Subgroups browsed into, the last one is listed
+	path []string
+	// Scroll offsets of the lists the subgroups of path were browsed into
+	// from, and the subgroup last browsed out of, restored when going back
+	offsets []int
+	back    string
+	output  tview.Primitive
+	// Incremented whenever the list is fetched, so only the latest fetch is
+	// shown
+	generation int
+}
+
+// listedGroup is a command group fetched for the list, or why it couldn't
+// be.
+type listedGroup struct {
+	name  string
+	group *azcli.CommandGroup
+	err   error
+}
+
+func NewCommandListView(layout *AppLayout, target azcli.Target) *CommandListView {
+	s := CommandListView{
+		List: tview.NewList(),
+	}
+
+	s.List.SetBorder(true)
+	s.ActionBarText = "## Select(Enter) ## | ## Exit(F12) ##"
+	s.Target = target
+	s.Parent = layout
+
+	s.Update()
+
+	return &s
+}
+
+// NewBulkCommandListView lists the az commands for targets, of the type of
+// the first one, and runs the one selected against all of them. Targets of
+// other types are left out.
+func NewBulkCommandListView(layout *AppLayout, targets []azcli.Target) *CommandListView {
+	s := NewCommandListView(layout, targets[0])
+
+	sameType := []azcli.Target{}
+	for _, target := range targets {
+		if strings.EqualFold(target.Type, targets[0].Type) {
+			sameType = append(sameType, target)
+		}
+	}
+	if len(sameType) > 1 {
+		s.Targets = sameType
+		s.Update()
+	}
+	return s
+}
+
+// targetName names the target in titles.
+func (s *CommandListView) targetName() string {
+	if len(s.Targets) > 1 {
+		return fmt.Sprintf("%v targets", len(s.Targets))
+	}
+	return s.Target.Name
+}
+
+// Update lists the commands again, keeping the scroll offset.
+func (s *CommandListView) Update() error {
+	offset, _ := s.List.GetOffset()
+	s.load(offset)
+	return nil
+}
+
+// load lists the commands of the groups managing the target, or of the
+// subgroup browsed into, scrolled to offset. The catalog is read in the
+// background since groups missing from its cache are looked up with az.
+func (s *CommandListView) load(offset int) {
+	s.generation++
+	generation := s.generation
+
+	s.List.Clear()
+	s.List.Box.SetTitle(fmt.Sprintf("%v Commands", s.targetName()))
+	s.List.AddItem("(Loading az commands...)", "", 0, nil)
+
+	groups := s.Groups
+	browsed := ""
+	if len(s.path) > 0 {
+		browsed = s.path[len(s.path)-1]
+	}
+	go func() {
+		if groups == nil {
+			groups = azcli.CommandGroupsForResourceType(s.Target.Type)
+		}
+
+		listed := []listedGroup{}
+		catalog, err := azcli.GetCatalog()
+		if err == nil && len(groups) > 0 {
+			names := groups
+			if browsed != "" {
+				names = []string{browsed}
+			}
+			for _, name := range names {
+				group, err := catalog.Group(name)
+				listed = append(listed, listedGroup{name, group, err})
+			}
+		}
+
+		queueUpdateDraw(func() {
+			if generation != s.generation {
+				return
+			}
+			s.Groups = groups
+			s.show(listed, err)
+			s.List.SetOffset(offset, 0)
+		})
+	}()
+}
+
+// show lists the commands and subgroups of the fetched groups.
+func (s *CommandListView) show(listed []listedGroup, err error) {
+	s.List.Clear()
+
+	if len(s.Groups) == 0 {
+		s.List.AddItem(fmt.Sprintf("(No az commands known for %v)", s.Target.Type), "", 0, nil)
+		return
+	}
+
+	if err != nil {
+		s.List.AddItem("(Unable to list az commands)", err.Error(), 0, nil)
+		return
+	}
+
+	if len(s.path) > 0 {
+		s.List.Box.SetTitle(fmt.Sprintf("az %v (%v)", s.path[len(s.path)-1], s.targetName()))
+		s.List.AddItem("..", "Back", 0, func() {
+			s.back = s.path[len(s.path)-1]
+			offset := s.offsets[len(s.offsets)-1]
+			s.path = s.path[:len(s.path)-1]
+			s.offsets = s.offsets[:len(s.offsets)-1]
+			s.load(offset)
+		})
+	} else {
+		s.List.Box.SetTitle(fmt.Sprintf("az %v (%v)", strings.Join(s.Groups, ", "), s.targetName()))
+	}
+
+	for _, l := range listed {
+		if l.err != nil {
+			s.List.AddItem("(Unable to list az commands)", l.err.Error(), 0, nil)
+			continue
+		}
+
+		prefix := ""
+		if len(listed) > 1 {
+			prefix = l.name + " "
+		}
+
+		for _, subgroup := range l.group.Subgroups {
+			subgroup := subgroup
+			label := prefix + strings.TrimPrefix(subgroup.Name, l.name+" ") + "/"
+			s.List.AddItem(label, commandGroupSummary(subgroup), 0, func() {
+				offset, _ := s.List.GetOffset()
+				s.path = append(s.path, subgroup.Name)
+				s.offsets = append(s.offsets, offset)
+				s.load(0)
+			})
+			if subgroup.Name == s.back {
+				s.List.SetCurrentItem(s.List.GetItemCount() - 1)
+			}
+		}
+
+		for _, command := range l.group.Commands {
+			command := command
+			s.List.AddItem(prefix+command.Name, commandSummary(command), 0, func() {
+				s.RunCommand(command.Path())
+			})
+		}
+	}
+}
+
+// RunCommand runs the az command with the given path against the target,
+// prompting for any arguments that can't be derived from it. The command is
+// looked up in the background, az is run for it when it isn't cached.
+func (s *CommandListView) RunCommand(path string) {
+	title := s.List.GetTitle()
+	s.List.SetTitle(fmt.Sprintf("%v - loading az %v", title, path))
+	generation := s.generation
+
+	go func() {
+		catalog, err := azcli.GetCatalog()
+		var command *azcli.Command
+		if err == nil {
+			command, err = catalog.Command(path)
+		}
+
+		queueUpdateDraw(func() {
+			if generation == s.generation {
+				s.List.SetTitle(title)
+			}
+			if err != nil {
+				s.ShowError(err)
+				return
+			}
+			s.runCommand(command)
+		})
+	}()
+}
+
+func (s *CommandListView) runCommand(command *azcli.Command) {
+	// Commands from subgroups manage child resources, the values derived
+	// from the parent are only a best guess, so always confirm them.
+	group := s.managingGroup(command)
+	if len(s.Targets) > 1 {
+		s.runOnTargets(command, group)
+		return
+	}
+	known := s.Target.KnownValues(*command, group)
+	PromptAndRunAzCommand(s.Parent, command, known, command.Group != group, s.ShowOutput)
+}
+
+// runOnTargets runs command against every target, always asking for its
+// values first since it acts on several resources at once.
+func (s *CommandListView) runOnTargets(command *azcli.Command, group string) {
+	known := []map[string]string{}
+	for _, target := range s.Targets {
+		known = append(known, target.KnownValues(*command, group))
+	}
+
+	PromptAndRunAzCommandOnTargets(s.Parent, command, known, func(args [][]string) {
+		bulk := NewBulkRunView(s.Parent, command.Path(), s.Targets, args)
+		s.Parent.ReplaceView(s.output, bulk.List, true, 3)
+		s.output = bulk.List
+		bulk.Run()
+	})
+}
+
+// The top level group the command was reached from
+func (s *CommandListView) managingGroup(command *azcli.Command) string {
+	for _, group := range s.Groups {
+		if command.Group == group || strings.HasPrefix(command.Group, group+" ") {
+			return group
+		}
+	}
+
+	return command.Group
+}
+
+// ShowOutput replaces the output of the previous command with output.
+func (s *CommandListView) ShowOutput(output *CommandOutputView) {
+	output.Target = s.Target.ID()
+	s.Parent.ReplaceView(s.output, output.Pages, true, 3)
+	s.output = output.Pages
+}
+
+func (s *CommandListView) ShowError(err error) {
+	output := tview.NewTextView()
+	output.SetTitle("Command Output")
+	output.SetBorder(true)
+	output.Write([]byte(fmt.Sprintf("Command execution failed with error: %v\n", err)))
+	s.Parent.ReplaceView(s.output, output, false, 3)
+	s.output = output
+}
+
+// Secondary text for a command in a command list, flagging preview,
+// experimental and deprecated commands.
+func commandSummary(command azcli.Command) string {
+	if command.Status == "" {
+		return command.Summary
+	}
+
+	return fmt.Sprintf("(%v) %v", command.Status, command.Summary)
+}
+
+func commandGroupSummary(group azcli.CommandGroup) string {
+	if group.Status == "" {
+		return group.Summary
+	}
+
+	return fmt.Sprintf("(%v) %v", group.Status, group.Summary)
+}
@@ -0,0 +1,72 @@
+package resourceviews
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/rivo/tview"
+)
+
+func TestCommandListViewLoadsInBackground(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake az is a shell script")
+	}
+
+	// az answering with the help of az 2.40.0, waiting for the test to let
+	// it
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	help, err := filepath.Abs(filepath.Join("..", "azcli", "testdata", "help", "2.40.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := filepath.Join(dir, "release")
+	script := "#!/bin/sh\nwhile [ ! -e " + release + " ]; do sleep 0.01; done\n" +
+		"case \"$1\" in\n" +
+		"version) echo '{\"azure-cli\": \"2.40.0\", \"extensions\": {}}' ;;\n" +
+		"*) cat " + help + "/$(echo \"$@\" | sed 's/ --help//; s/ /_/g').txt ;;\n" +
+		"esac\n"
+	az := filepath.Join(dir, "az")
+	if err := os.WriteFile(az, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "aztui.yaml")
+	if err := os.WriteFile(path, []byte("azcli:\n  path: "+az+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.LoadConfig("") })
+
+	updates := useQueuedUpdates(t)
+	layout := &AppLayout{App: tview.NewApplication(), ActionBar: tview.NewTextView()}
+
+	// Returns while az is still running
+	s := NewCommandListView(layout, azcli.Target{Type: "Microsoft.Compute/virtualMachines", Name: "vm"})
+	if main, _ := s.List.GetItemText(0); s.List.GetItemCount() != 1 || main != "(Loading az commands...)" {
+		t.Fatalf("list %q while loading", main)
+	}
+
+	if err := os.WriteFile(release, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	(<-updates)()
+
+	commands := []string{}
+	for i := 0; i < s.List.GetItemCount(); i++ {
+		main, _ := s.List.GetItemText(i)
+		commands = append(commands, main)
+	}
+	if !strings.Contains(strings.Join(commands, ","), "stop") {
+		t.Errorf("commands %q, want vm stop among them", commands)
+	}
+	if title := s.List.GetTitle(); title != "az vm (vm)" {
+		t.Errorf("title %q", title)
+	}
+}
@@ -0,0 +1,228 @@
+package resourceviews
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/dryrun"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var commandOutputFuncMap = map[string]func(*CommandOutputView) tview.Primitive{
+	"CancelCommand":   (*CommandOutputView).CancelCommand,
+	"ToggleRawOutput": (*CommandOutputView).ToggleRawOutput,
+	"SortOutput":      (*CommandOutputView).SortOutput,
+}
+
+// CommandOutputView runs an az command in the background and streams its
+// output into a text view as it is produced. Once the command succeeds, JSON
+// output is rendered with a JSONView and the streamed text stays available
+// through ToggleRawOutput.
+type CommandOutputView struct {
+	Pages    *tview.Pages
+	TextView *tview.TextView
+	JSON     *JSONView
+	Args     []string
+	// ID of the resource the command runs against, recorded in the history
+	Target    string
+	Parent    *AppLayout
+	execution *azcli.Execution
+	showRaw   bool
+}
+
+func NewCommandOutputView(layout *AppLayout, args []string) *CommandOutputView {
+	c := CommandOutputView{
+		Pages:    tview.NewPages(),
+		TextView: tview.NewTextView(),
+		Args:     args,
+		Parent:   layout,
+	}
+
+	c.TextView.SetTitle("Command Output")
+	c.TextView.SetBorder(true)
+	c.TextView.SetDynamicColors(true)
+	c.TextView.SetScrollable(true)
+
+	c.TextView.SetFocusFunc(func() {
+		c.UpdateActionBar(c.Parent.ActionBar)
+	})
+	c.Pages.AddPage("output", c.TextView, true, true)
+	InitViewKeyBindings(&c)
+
+	return &c
+}
+
+func (c *CommandOutputView) UpdateActionBar(t *tview.TextView) {
+	actionBarText := ""
+	for _, view := range config.Current().Views {
+		if view.Name == c.Name() {
+			for _, action := range view.Actions {
+				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
+			}
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
+			break
+		}
+	}
+
+	t.SetText(actionBarText)
+}
+
+func (c *CommandOutputView) Name() string {
+	return "CommandOutputView"
+}
+
+func (c *CommandOutputView) Update() error {
+	return nil
+}
+
+func (c *CommandOutputView) SetInputCapture(f func(event *tcell.EventKey) *tcell.EventKey) {
+	c.Pages.SetInputCapture(f)
+}
+
+func (c *CommandOutputView) CustomInputHandler() func(event *tcell.EventKey) *tcell.EventKey {
+	return nil
+}
+
+func (c *CommandOutputView) CallAction(action string) (tview.Primitive, error) {
+	if actionFunc, ok := commandOutputFuncMap[action]; ok {
+		return actionFunc(c), nil
+	}
+	return nil, fmt.Errorf("no action for %s", action)
+}
+
+func (c *CommandOutputView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
+	c.Parent.AppendPrimitiveView(p, takeFocus, width)
+}
+
+// Run starts the command. onExit is called on the UI goroutine once the
+// command exited and its output has been written to the view. In dry-run
+// mode mutating commands are shown for confirmation first, or only shown,
+// and onExit isn't called when they don't run.
+func (c *CommandOutputView) Run(onExit func(azcli.Result)) {
+	c.TextView.Clear()
+	fmt.Fprintf(c.TextView, "[::b]$ %v[::-]\n", tview.Escape(azcli.ShellCommand(c.Args)))
+
+	if azcli.IsReadOnlyCommand(c.Args) {
+		c.start(onExit)
+		return
+	}
+
+	switch dryrun.CurrentMode() {
+	case dryrun.Strict:
+		fmt.Fprintln(c.TextView, warningText("Dry run, the command was not executed"))
+		c.TextView.SetTitle("Command Output (dry run)")
+	case dryrun.Preview:
+		c.TextView.SetTitle("Command Output (waiting for confirmation)")
+		c.Parent.ShowPreview(dryrun.Request{CommandLine: azcli.ShellCommand(c.Args)}, func() {
+			c.start(onExit)
+		}, func() {
+			fmt.Fprintln(c.TextView, warningText("Not confirmed, the command was not executed"))
+			c.TextView.SetTitle("Command Output (not executed)")
+		})
+	default:
+		c.start(onExit)
+	}
+}
+
+func (c *CommandOutputView) start(onExit func(azcli.Result)) {
+	c.TextView.SetTitle("Command Output (running)")
+
+	e, err := azcli.StartAzCommand(context.Background(), c.Args, func(stream azcli.Stream, line string) {
+		line = tview.TranslateANSI(tview.Escape(line))
+		if stream == azcli.Stderr {
+			line = errorText(line)
+		}
+		c.Parent.App.QueueUpdateDraw(func() {
+			fmt.Fprintln(c.TextView, line)
+		})
+	}, func(result azcli.Result) {
+		if err := azcli.AppendHistory(azcli.NewHistoryEntry(result, c.Target)); err != nil {
+			logger.Warn("Failed to record command history", "err", err)
+		}
+		c.Parent.App.QueueUpdateDraw(func() {
+			c.showResult(result)
+			if onExit != nil {
+				onExit(result)
+			}
+		})
+	})
+
+	if err != nil {
+		fmt.Fprintln(c.TextView, errorText(tview.Escape(err.Error())))
+		c.TextView.SetTitle("Command Output (failed)")
+		return
+	}
+
+	c.execution = e
+}
+
+func (c *CommandOutputView) showResult(result azcli.Result) {
+	status := successText(fmt.Sprintf("exit %v", result.ExitCode))
+	switch {
+	case result.Canceled:
+		status = warningText("canceled")
+	case result.ExitCode != 0:
+		status = errorText(fmt.Sprintf("exit %v", result.ExitCode))
+	}
+
+	fmt.Fprintf(c.TextView, "\n%v after %v\n", status, result.Duration.Round(10*time.Millisecond))
+	c.TextView.SetTitle(fmt.Sprintf("Command Output (%v)", status))
+	c.TextView.ScrollToEnd()
+
+	if result.ExitCode != 0 || strings.TrimSpace(result.Stdout) == "" {
+		return
+	}
+
+	value, err := DecodeJSON([]byte(result.Stdout))
+	if err != nil {
+		return
+	}
+
+	hadFocus := c.TextView.HasFocus()
+	c.JSON = NewJSONView(c.Parent.App, "az "+strings.Join(c.Args, " "), value)
+	c.JSON.SetFocusFunc(func() {
+		c.UpdateActionBar(c.Parent.ActionBar)
+	})
+	c.Pages.AddAndSwitchToPage("json", c.JSON.Pages, true)
+	if hadFocus {
+		c.Parent.App.SetFocus(c.Pages)
+	}
+}
+
+// ToggleRawOutput switches between the structured rendering of the JSON
+// output and the text az printed.
+func (c *CommandOutputView) ToggleRawOutput() tview.Primitive {
+	if c.JSON == nil {
+		return nil
+	}
+
+	c.showRaw = !c.showRaw
+	if c.showRaw {
+		c.Pages.SwitchToPage("output")
+	} else {
+		c.Pages.SwitchToPage("json")
+	}
+	c.Parent.App.SetFocus(c.Pages)
+
+	return nil
+}
+
+func (c *CommandOutputView) SortOutput() tview.Primitive {
+	if c.JSON != nil && !c.showRaw {
+		c.JSON.SortBySelectedColumn()
+	}
+	return nil
+}
+
+func (c *CommandOutputView) CancelCommand() tview.Primitive {
+	if c.execution != nil && c.execution.Running() {
+		c.execution.Cancel()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package resourceviews
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/rivo/tview"
+)
+
+const configProblemsModal = "configProblems"
+
+// How often the configuration file is checked for changes
+const configWatchInterval = 2 * time.Second
+
+// WatchConfig reloads the configuration from path whenever the file changes.
+func (a *AppLayout) WatchConfig(path string) {
+	a.configPath = path
+
+	go func() {
+		modTime := configModTime(path)
+		for {
+			time.Sleep(configWatchInterval)
+			if t := configModTime(path); !t.Equal(modTime) {
+				modTime = t
+				a.App.QueueUpdateDraw(func() {
+					a.ReloadConfig()
+				})
+			}
+		}
+	}()
+}
+
+// Zero when the file doesn't exist, so creating it counts as a change
+func configModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// ReloadConfig re-reads the configuration file. A configuration with
+// problems is not applied, the problems are shown instead and the previous
+// configuration stays in effect.
+func (a *AppLayout) ReloadConfig() tview.Primitive {
+	if problems := config.ValidateFile(a.configPath, ConfigSchema()); len(problems) > 0 {
+		logger.Warn("Not reloading the configuration", "path", a.configPath, "problems", problems.Error())
+		a.showConfigProblems(problems.Error())
+		return nil
+	}
+
+	if _, err := config.LoadConfig(a.configPath); err != nil {
+		logger.Warn("Not reloading the configuration", "path", a.configPath, "err", err)
+		a.showConfigProblems(err.Error())
+		return nil
+	}
+	logger.Info("Reloaded the configuration", "path", a.configPath)
+	applyTheme()
+	a.restyle()
+
+	// Key bindings are looked up when keys are pressed, so only the action
+	// bar of the focused view has to be redrawn, which its focus func does
+	if focused := a.App.GetFocus(); focused != nil && !a.HasModal() {
+		a.App.SetFocus(focused)
+	}
+
+	return nil
+}
+
+func (a *AppLayout) showConfigProblems(problems string) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("The configuration was not reloaded:\n\n%v", tview.Escape(problems))).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(int, string) {
+			a.HideModal(configProblemsModal)
+		})
+
+	a.HideModal(configProblemsModal)
+	a.ShowModal(configProblemsModal, modal, 0, 0)
+}
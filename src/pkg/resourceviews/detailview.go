@@ -0,0 +1,138 @@
+package resourceviews
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var resourceDetailFuncMap = map[string]func(*ResourceDetailView) tview.Primitive{
+	"ToggleRawOutput": (*ResourceDetailView).ToggleRawOutput,
+}
+
+// ResourceDetailView shows an ARM resource as returned by the SDK, using the
+// same JSON rendering as the output of az commands. The resource is fetched
+// in the background, with a text view showing progress until then and why it
+// couldn't be fetched.
+type ResourceDetailView struct {
+	Pages    *tview.Pages
+	TextView *tview.TextView
+	// Rendering of the resource, nil until it is fetched
+	JSON   *JSONView
+	Parent *AppLayout
+	title  string
+}
+
+// NewResourceDetailView renders the resource fetch returns, any SDK model
+// that marshals to the ARM representation of a resource. fetch runs off the
+// UI goroutine.
+func NewResourceDetailView(layout *AppLayout, title string, fetch func() (interface{}, error)) *ResourceDetailView {
+	d := ResourceDetailView{
+		Pages:    tview.NewPages(),
+		TextView: tview.NewTextView(),
+		Parent:   layout,
+		title:    title,
+	}
+
+	d.TextView.SetTitle(title)
+	d.TextView.SetBorder(true)
+	d.TextView.SetDynamicColors(true)
+	d.TextView.SetText("Loading...")
+	d.TextView.SetFocusFunc(func() {
+		d.UpdateActionBar(d.Parent.ActionBar)
+	})
+	d.Pages.AddPage("status", d.TextView, true, true)
+	InitViewKeyBindings(&d)
+
+	go func() {
+		resource, err := fetch()
+		queueUpdateDraw(func() {
+			d.show(resource, err)
+		})
+	}()
+
+	return &d
+}
+
+// show renders the fetched resource, or the error fetching it.
+func (d *ResourceDetailView) show(resource interface{}, err error) {
+	var value interface{}
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(resource)
+		if err == nil {
+			value, err = DecodeJSON(data)
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to get resource", "title", d.title, "err", err)
+		d.TextView.SetText(errorText(tview.Escape(err.Error())))
+		d.TextView.SetTitle(d.title + " (failed)")
+		return
+	}
+
+	hadFocus := d.TextView.HasFocus()
+	d.JSON = NewJSONView(d.Parent.App, d.title, value)
+	d.JSON.SetFocusFunc(func() {
+		d.UpdateActionBar(d.Parent.ActionBar)
+	})
+	d.Pages.AddAndSwitchToPage("json", d.JSON.Pages, true)
+	if hadFocus {
+		d.Parent.App.SetFocus(d.Pages)
+	}
+}
+
+func (d *ResourceDetailView) UpdateActionBar(t *tview.TextView) {
+	actionBarText := ""
+	for _, view := range config.Current().Views {
+		if view.Name == d.Name() {
+			for _, action := range view.Actions {
+				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
+			}
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
+			break
+		}
+	}
+
+	t.SetText(actionBarText)
+}
+
+func (d *ResourceDetailView) Name() string {
+	return "ResourceDetailView"
+}
+
+func (d *ResourceDetailView) Update() error {
+	return nil
+}
+
+func (d *ResourceDetailView) SetInputCapture(f func(event *tcell.EventKey) *tcell.EventKey) {
+	d.Pages.SetInputCapture(f)
+}
+
+func (d *ResourceDetailView) CustomInputHandler() func(event *tcell.EventKey) *tcell.EventKey {
+	return nil
+}
+
+func (d *ResourceDetailView) CallAction(action string) (tview.Primitive, error) {
+	if actionFunc, ok := resourceDetailFuncMap[action]; ok {
+		return actionFunc(d), nil
+	}
+	return nil, fmt.Errorf("no action for %s", action)
+}
+
+func (d *ResourceDetailView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
+	d.Parent.AppendPrimitiveView(p, takeFocus, width)
+}
+
+func (d *ResourceDetailView) ToggleRawOutput() tview.Primitive {
+	if d.JSON == nil {
+		return nil
+	}
+	d.JSON.ToggleRaw()
+	return nil
+}
@@ -0,0 +1,62 @@
+package resourceviews
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+// useQueuedUpdates makes queueUpdateDraw hand the updates to the test.
+func useQueuedUpdates(t *testing.T) chan func() {
+	updates := make(chan func(), 1)
+	previous := queueUpdateDraw
+	queueUpdateDraw = func(f func()) { updates <- f }
+	t.Cleanup(func() { queueUpdateDraw = previous })
+	return updates
+}
+
+func TestResourceDetailViewFetchFailed(t *testing.T) {
+	updates := useQueuedUpdates(t)
+	layout := &AppLayout{App: tview.NewApplication(), ActionBar: tview.NewTextView()}
+
+	d := NewResourceDetailView(layout, "vm Details", func() (interface{}, error) {
+		return nil, errors.New("AuthorizationFailed")
+	})
+	if text := d.TextView.GetText(true); text != "Loading..." {
+		t.Errorf("text before the fetch = %q, want Loading...", text)
+	}
+
+	(<-updates)()
+	if text := d.TextView.GetText(true); !strings.Contains(text, "AuthorizationFailed") {
+		t.Errorf("text = %q, want the error", text)
+	}
+	if title := d.TextView.GetTitle(); title != "vm Details (failed)" {
+		t.Errorf("title = %q", title)
+	}
+	if d.JSON != nil {
+		t.Error("failed resource rendered")
+	}
+	// Nothing to toggle
+	d.ToggleRawOutput()
+}
+
+func TestResourceDetailViewFetched(t *testing.T) {
+	updates := useQueuedUpdates(t)
+	layout := &AppLayout{App: tview.NewApplication(), ActionBar: tview.NewTextView()}
+
+	d := NewResourceDetailView(layout, "vm Details", func() (interface{}, error) {
+		return struct {
+			Name string `json:"name"`
+		}{"vm"}, nil
+	})
+
+	(<-updates)()
+	if d.JSON == nil {
+		t.Fatal("resource not rendered")
+	}
+	if name, _ := d.Pages.GetFrontPage(); name != "json" {
+		t.Errorf("front page %q, want json", name)
+	}
+}
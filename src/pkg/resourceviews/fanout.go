@@ -0,0 +1,84 @@
+package resourceviews
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+)
+
+// subscriptionScope is the cache scope of a listing across subscriptions.
+func subscriptionScope(subscriptions []listItem) string {
+	ids := []string{}
+	for _, subscription := range subscriptions {
+		ids = append(ids, subscription.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// fanOut lists the items of every one of subscriptions with fetch, at most
+// arm.maxConcurrentSubscriptions at a time, and merges them in the order of
+// subscriptions with the subscription of each item. A subscription that
+// can't be listed is shown as an error row rather than failing the listing,
+// which only fails when no subscription can be listed.
+func fanOut(subscriptions []listItem, fetch func(subscriptionID string) ([]listItem, error)) ([]listItem, error) {
+	results := make([][]listItem, len(subscriptions))
+	errs := make([]error, len(subscriptions))
+	runBounded(len(subscriptions), func(i int) {
+		results[i], errs[i] = fetch(subscriptions[i].ID)
+	})
+
+	items := []listItem{}
+	failed := 0
+	for i, subscription := range subscriptions {
+		if errs[i] != nil {
+			logger.Error("Failed to list subscription", "subscription", subscription.ID, "err", errs[i])
+			failed++
+			items = append(items, listItem{
+				Name:         fmt.Sprintf("(%v)", errs[i]),
+				ID:           subscription.ID,
+				Subscription: subscription.Name,
+				Err:          errs[i].Error(),
+			})
+			continue
+		}
+		for _, item := range results[i] {
+			item.Subscription = subscription.Name
+			items = append(items, item)
+		}
+	}
+
+	if failed > 0 && failed == len(subscriptions) {
+		return nil, fmt.Errorf("no subscription could be listed: %v", errs[0])
+	}
+	return items, nil
+}
+
+// runBounded calls f with every index below n, at most
+// arm.maxConcurrentSubscriptions at a time, and returns once every call
+// returned. It bounds the Resource Manager reads a listing issues at once.
+func runBounded(n int, f func(i int)) {
+	workers := config.Current().ARM.MaxConcurrentSubscriptions
+	if workers <= 0 {
+		workers = 1
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,145 @@
+package resourceviews
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+// useConfig loads the configuration yaml over the defaults for the test.
+func useConfig(t *testing.T, yaml string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "aztui.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.LoadConfig("") })
+}
+
+// concurrency tracks how many calls run at the same time.
+type concurrency struct {
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (c *concurrency) enter() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running++
+	if c.running > c.max {
+		c.max = c.running
+	}
+}
+
+func (c *concurrency) leave() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running--
+}
+
+func TestFanOut(t *testing.T) {
+	subscriptions := []listItem{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		subscriptions = append(subscriptions, listItem{Name: "sub-" + name, ID: name})
+	}
+
+	tests := []struct {
+		workers int
+		// Subscriptions whose listing fails
+		failing map[string]bool
+		want    []listItem
+		err     bool
+	}{
+		{
+			workers: 2,
+			failing: map[string]bool{"c": true},
+			want: []listItem{
+				{Name: "a-vm", Subscription: "sub-a"},
+				{Name: "b-vm", Subscription: "sub-b"},
+				{Name: "(403 on c)", ID: "c", Subscription: "sub-c", Err: "403 on c"},
+				{Name: "d-vm", Subscription: "sub-d"},
+				{Name: "e-vm", Subscription: "sub-e"},
+				{Name: "f-vm", Subscription: "sub-f"},
+			},
+		},
+		{
+			workers: 4,
+			failing: map[string]bool{"a": true, "f": true},
+			want: []listItem{
+				{Name: "(403 on a)", ID: "a", Subscription: "sub-a", Err: "403 on a"},
+				{Name: "b-vm", Subscription: "sub-b"},
+				{Name: "c-vm", Subscription: "sub-c"},
+				{Name: "d-vm", Subscription: "sub-d"},
+				{Name: "e-vm", Subscription: "sub-e"},
+				{Name: "(403 on f)", ID: "f", Subscription: "sub-f", Err: "403 on f"},
+			},
+		},
+		{
+			// Unset runs one at a time
+			workers: 0,
+			failing: map[string]bool{},
+			want: []listItem{
+				{Name: "a-vm", Subscription: "sub-a"},
+				{Name: "b-vm", Subscription: "sub-b"},
+				{Name: "c-vm", Subscription: "sub-c"},
+				{Name: "d-vm", Subscription: "sub-d"},
+				{Name: "e-vm", Subscription: "sub-e"},
+				{Name: "f-vm", Subscription: "sub-f"},
+			},
+		},
+		{
+			workers: 8,
+			failing: map[string]bool{"a": true, "b": true, "c": true, "d": true, "e": true, "f": true},
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		useConfig(t, fmt.Sprintf("arm:\n  maxConcurrentSubscriptions: %v\n", tt.workers))
+
+		var c concurrency
+		items, err := fanOut(subscriptions, func(subscriptionID string) ([]listItem, error) {
+			c.enter()
+			defer c.leave()
+			time.Sleep(20 * time.Millisecond)
+
+			if tt.failing[subscriptionID] {
+				return nil, errors.New("403 on " + subscriptionID)
+			}
+			return []listItem{{Name: subscriptionID + "-vm"}}, nil
+		})
+
+		if tt.err {
+			if err == nil {
+				t.Errorf("%v workers: listed %v, want an error", tt.workers, items)
+			}
+		} else if err != nil {
+			t.Errorf("%v workers: %v", tt.workers, err)
+		} else if !reflect.DeepEqual(items, tt.want) {
+			t.Errorf("%v workers: listed\n%+v\nwant\n%+v", tt.workers, items, tt.want)
+		}
+
+		limit := tt.workers
+		if limit <= 0 {
+			limit = 1
+		}
+		if c.max > limit {
+			t.Errorf("%v workers: %v subscriptions listed at once", tt.workers, c.max)
+		}
+		if c.max < 2 && limit > 1 {
+			t.Errorf("%v workers: subscriptions listed one at a time", tt.workers)
+		}
+	}
+}
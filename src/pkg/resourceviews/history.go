@@ -0,0 +1,306 @@
+package resourceviews
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/utils"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const historyModal = "history"
+
+var historySelectItemFuncMap = map[string]func(*HistoryListView) tview.Primitive{
+	"RerunCommand":       (*HistoryListView).RerunCommand,
+	"EditAndRunCommand":  (*HistoryListView).EditAndRunCommand,
+	"CopyCommand":        (*HistoryListView).CopyCommand,
+	"ShowHistoryOutput":  (*HistoryListView).ShowHistoryOutput,
+	"CloseHistoryView":   (*HistoryListView).CloseHistoryView,
+	"RefreshHistoryView": (*HistoryListView).RefreshHistoryView,
+}
+
+// HistoryListView lists the az commands executed through aztui, newest first.
+type HistoryListView struct {
+	List          *tview.List
+	StatusBarText string
+	ActionBarText string
+	Parent        *AppLayout
+	History       []azcli.HistoryEntry
+	// Indices into History of the listed entries, after filtering
+	listed []int
+	output tview.Primitive
+	// When the entry last selected ran, kept while it is filtered out
+	selection time.Time
+	// Set while UpdateList fills the list, see listing
+	rendering bool
+}
+
+func NewHistoryListView(layout *AppLayout) *HistoryListView {
+	h := HistoryListView{
+		List: tview.NewList(),
+	}
+
+	h.List.SetBorder(true)
+	h.List.Box.SetTitle("Command History")
+	h.List.ShowSecondaryText(true)
+	h.Parent = layout
+
+	h.List.SetChangedFunc(func(index int, _, _ string, _ rune) {
+		if !h.rendering && index < len(h.listed) {
+			h.selection = h.History[h.listed[index]].Timestamp
+		}
+	})
+	h.List.SetFocusFunc(func() {
+		InitViewKeyBindings(&h)
+		if i := h.Parent.IndexOf(h.List); i != -1 {
+			h.Parent.FocusedViewIndex = i
+		}
+		if h.History == nil {
+			h.Update()
+		}
+		h.UpdateList(h.Parent)
+		h.UpdateActionBar(h.Parent.ActionBar)
+	})
+
+	return &h
+}
+
+func (h *HistoryListView) UpdateActionBar(t *tview.TextView) {
+	actionBarText := ""
+	for _, view := range config.Current().Views {
+		if view.Name == h.Name() {
+			for _, action := range view.Actions {
+				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
+			}
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
+			break
+		}
+	}
+
+	t.SetText(actionBarText)
+}
+
+func (h *HistoryListView) Name() string {
+	return "HistoryListView"
+}
+
+func (h *HistoryListView) SetInputCapture(f func(event *tcell.EventKey) *tcell.EventKey) {
+	h.List.SetInputCapture(f)
+}
+
+func (h *HistoryListView) CustomInputHandler() func(event *tcell.EventKey) *tcell.EventKey {
+	return nil
+}
+
+func (h *HistoryListView) CallAction(action string) (tview.Primitive, error) {
+	if actionFunc, ok := historySelectItemFuncMap[action]; ok {
+		return actionFunc(h), nil
+	}
+	return nil, fmt.Errorf("no action for %s", action)
+}
+
+func (h *HistoryListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
+	h.Parent.AppendPrimitiveView(p, takeFocus, width)
+}
+
+func (h *HistoryListView) Update() error {
+	history, err := azcli.LoadHistory()
+	if err != nil {
+		h.History = []azcli.HistoryEntry{}
+		h.listed = []int{}
+		h.List.Clear()
+		h.List.AddItem("(Unable to load command history)", err.Error(), 0, nil)
+		return err
+	}
+
+	h.History = history
+	return nil
+}
+
+// UpdateList lists the entries whose command line or target contain the
+// search text, keeping the selected entry selected and the list scrolled
+// where it was.
+func (h *HistoryListView) UpdateList(layout *AppLayout) error {
+	if entry, ok := h.selected(); ok && h.selection.IsZero() {
+		h.selection = entry.Timestamp
+	}
+	offset, _ := h.List.GetOffset()
+	h.rendering = true
+	defer func() {
+		h.rendering = false
+	}()
+
+	h.List.Clear()
+	h.listed = []int{}
+	current := -1
+
+	// Make filtering case insensitive
+	filter := strings.ToLower(layout.InputField.GetText())
+	for i := len(h.History) - 1; i >= 0; i-- {
+		entry := h.History[i]
+		command := entry.ShellCommand()
+		if !strings.Contains(strings.ToLower(command+" "+entry.Target), filter) {
+			continue
+		}
+
+		status := fmt.Sprintf("exit %v", entry.ExitCode)
+		if entry.Canceled {
+			status = "canceled"
+		}
+		secondary := fmt.Sprintf("%v | %v | %v", entry.Timestamp.Local().Format("2006-01-02 15:04:05"), status, entry.Duration.Round(10*time.Millisecond))
+		if entry.Target != "" {
+			secondary += " | " + entry.Target
+		}
+
+		h.List.AddItem(tview.Escape(command), tview.Escape(secondary), 0, nil)
+		if entry.Timestamp.Equal(h.selection) {
+			current = len(h.listed)
+		}
+		h.listed = append(h.listed, i)
+	}
+
+	if len(h.listed) == 0 {
+		h.List.AddItem("(No commands in history)", "", 0, nil)
+	}
+	if current != -1 {
+		h.List.SetOffset(offset, 0)
+		h.List.SetCurrentItem(current)
+	}
+
+	return nil
+}
+
+// add lists entry, recorded since the history was loaded, keeping at most
+// as many entries as the history file.
+func (h *HistoryListView) add(entry azcli.HistoryEntry) {
+	if h.History == nil {
+		// Loaded with entry once the view is focused
+		return
+	}
+
+	h.History = append(h.History, entry)
+	if limit := azcli.MaxHistoryEntries(); limit > 0 && len(h.History) > limit {
+		h.History = h.History[len(h.History)-limit:]
+	}
+	h.UpdateList(h.Parent)
+}
+
+func (h *HistoryListView) selected() (azcli.HistoryEntry, bool) {
+	current := h.List.GetCurrentItem()
+	if current < 0 || current >= len(h.listed) {
+		return azcli.HistoryEntry{}, false
+	}
+
+	return h.History[h.listed[current]], true
+}
+
+func (h *HistoryListView) run(args []string, target string) {
+	output := NewCommandOutputView(h.Parent, args)
+	output.Target = target
+	h.Parent.ReplaceView(h.output, output.Pages, true, 3)
+	h.output = output.Pages
+	output.Run(nil)
+}
+
+func (h *HistoryListView) RerunCommand() tview.Primitive {
+	if entry, ok := h.selected(); ok {
+		h.run(entry.Args, entry.Target)
+	}
+	return nil
+}
+
+// EditAndRunCommand opens the selected command line for editing and runs
+// the result.
+func (h *HistoryListView) EditAndRunCommand() tview.Primitive {
+	entry, ok := h.selected()
+	if !ok {
+		return nil
+	}
+
+	form := tview.NewForm()
+	form.SetTitle("Edit Command")
+	form.SetBorder(true)
+	field := tview.NewInputField().
+		SetLabel("$ ").
+		SetText(entry.ShellCommand())
+	form.AddFormItem(field)
+	form.AddButton("Run", func() {
+		args, err := azcli.SplitShellCommand(field.GetText())
+		if err != nil {
+			form.SetTitle("Edit Command - " + errorText(tview.Escape(err.Error())))
+			return
+		}
+		if len(args) == 0 {
+			form.SetTitle("Edit Command - " + errorText("empty command"))
+			return
+		}
+		h.Parent.HideModal(historyModal)
+		h.run(args, entry.Target)
+	})
+	cancel := func() {
+		h.Parent.HideModal(historyModal)
+	}
+	form.AddButton("Cancel", cancel)
+	form.SetCancelFunc(cancel)
+
+	h.Parent.ShowModal(historyModal, form, 120, 7)
+	return nil
+}
+
+// CopyCommand puts the selected command line on the clipboard. Without a
+// clipboard tool the command line is shown so it can be selected by hand.
+func (h *HistoryListView) CopyCommand() tview.Primitive {
+	entry, ok := h.selected()
+	if !ok {
+		return nil
+	}
+
+	if err := utils.CopyToClipboard(entry.ShellCommand()); err != nil {
+		modal := tview.NewModal().
+			SetText(fmt.Sprintf("Unable to copy to the clipboard: %v\n\n%v", err, entry.ShellCommand())).
+			AddButtons([]string{"OK"}).
+			SetDoneFunc(func(int, string) {
+				h.Parent.HideModal(historyModal)
+			})
+		h.Parent.ShowModal(historyModal, modal, 0, 0)
+	}
+
+	return nil
+}
+
+// ShowHistoryOutput shows the output captured when the selected command ran.
+func (h *HistoryListView) ShowHistoryOutput() tview.Primitive {
+	entry, ok := h.selected()
+	if !ok {
+		return nil
+	}
+
+	output := tview.NewTextView()
+	output.SetTitle(tview.Escape(entry.ShellCommand()))
+	output.SetBorder(true)
+	output.SetScrollable(true)
+	output.SetText(entry.Output)
+	h.Parent.ReplaceView(h.output, output, true, 3)
+	h.output = output
+
+	return nil
+}
+
+func (h *HistoryListView) RefreshHistoryView() tview.Primitive {
+	h.Update()
+	h.UpdateList(h.Parent)
+	return nil
+}
+
+func (h *HistoryListView) CloseHistoryView() tview.Primitive {
+	if h.output != nil {
+		h.Parent.Layout.RemoveItem(h.output)
+	}
+	h.Parent.Layout.RemoveItem(h.List)
+	h.Parent.FocusView(0)
+	return nil
+}
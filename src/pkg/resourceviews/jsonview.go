@@ -0,0 +1,332 @@
+package resourceviews
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// JSONView renders a JSON document. Arrays of objects are shown as a table
+// that can be sorted by any column, with Enter opening the selected row as a
+// tree; everything else is shown as a collapsible tree. The pretty printed
+// document is available as raw text through ToggleRaw.
+type JSONView struct {
+	App        *tview.Application
+	Pages      *tview.Pages
+	Table      *tview.Table
+	Tree       *tview.TreeView
+	Raw        *tview.TextView
+	title      string
+	value      interface{}
+	rows       []interface{}
+	columns    []string
+	sortColumn int
+	sortDesc   bool
+	showingRaw bool
+	focusFunc  func()
+}
+
+// DecodeJSON decodes data keeping numbers as json.Number, so large integers
+// and the original formatting of numbers survive rendering.
+func DecodeJSON(data []byte) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func NewJSONView(app *tview.Application, title string, value interface{}) *JSONView {
+	j := JSONView{
+		App:        app,
+		Pages:      tview.NewPages(),
+		Raw:        tview.NewTextView(),
+		title:      title,
+		value:      value,
+		sortColumn: -1,
+	}
+
+	raw, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		raw = []byte(err.Error())
+	}
+	j.Raw.SetText(string(raw))
+	j.Raw.SetTitle(title + " (raw)")
+	j.Raw.SetBorder(true)
+	j.Raw.SetScrollable(true)
+	j.Pages.AddPage("raw", j.Raw, true, false)
+
+	if rows, columns := tableColumns(value); len(columns) > 0 {
+		j.rows = rows
+		j.columns = columns
+		j.Table = tview.NewTable()
+		j.Table.SetTitle(title)
+		j.Table.SetBorder(true)
+		j.Table.SetFixed(1, 0)
+		j.Table.SetSelectable(true, true)
+		j.Table.SetSelectedStyle(selectionStyle())
+		j.Table.SetSelectedFunc(func(row, column int) {
+			if row > 0 && row <= len(j.rows) {
+				j.showItem(j.rows[row-1])
+			}
+		})
+		j.renderTable()
+		j.Pages.AddPage("table", j.Table, true, true)
+	} else {
+		j.Tree = newJSONTree(title, value)
+		j.Pages.AddPage("tree", j.Tree, true, true)
+	}
+
+	return &j
+}
+
+// SetFocusFunc sets a callback invoked whenever any page of the view gets
+// focus.
+func (j *JSONView) SetFocusFunc(f func()) {
+	j.focusFunc = f
+	j.Raw.SetFocusFunc(f)
+	if j.Table != nil {
+		j.Table.SetFocusFunc(f)
+	}
+	if j.Tree != nil {
+		j.Tree.SetFocusFunc(f)
+	}
+}
+
+// ToggleRaw switches between the structured rendering and the raw JSON.
+func (j *JSONView) ToggleRaw() {
+	j.showingRaw = !j.showingRaw
+	if j.showingRaw {
+		j.Pages.ShowPage("raw")
+		j.Pages.SendToFront("raw")
+	} else {
+		j.Pages.HidePage("raw")
+	}
+	j.App.SetFocus(j.Pages)
+}
+
+// SortBySelectedColumn sorts the table by the column of the selected cell,
+// reversing the order when it already is the sort column.
+func (j *JSONView) SortBySelectedColumn() {
+	if j.Table == nil || j.showingRaw {
+		return
+	}
+
+	_, column := j.Table.GetSelection()
+	if column == j.sortColumn {
+		j.sortDesc = !j.sortDesc
+	} else {
+		j.sortColumn = column
+		j.sortDesc = false
+	}
+
+	key := j.columns[column]
+	sort.SliceStable(j.rows, func(a, b int) bool {
+		less := compareJSONValues(jsonField(j.rows[a], key), jsonField(j.rows[b], key))
+		if j.sortDesc {
+			return less > 0
+		}
+		return less < 0
+	})
+
+	j.renderTable()
+}
+
+func (j *JSONView) renderTable() {
+	j.Table.Clear()
+	for c, column := range j.columns {
+		header := column
+		if c == j.sortColumn {
+			header += map[bool]string{false: " ▲", true: " ▼"}[j.sortDesc]
+		}
+		j.Table.SetCell(0, c, tview.NewTableCell(tview.Escape(header)).
+			SetAttributes(tcell.AttrBold).
+			SetSelectable(false))
+	}
+
+	for r, row := range j.rows {
+		for c, column := range j.columns {
+			j.Table.SetCell(r+1, c, tview.NewTableCell(tview.Escape(jsonScalarText(jsonField(row, column)))).
+				SetMaxWidth(40))
+		}
+	}
+}
+
+func (j *JSONView) showItem(item interface{}) {
+	tree := newJSONTree(j.title, item)
+	tree.SetFocusFunc(j.focusFunc)
+	tree.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			j.Pages.RemovePage("item")
+			j.App.SetFocus(j.Pages)
+		}
+	})
+	tree.SetTitle(j.title + " (Esc to return)")
+	j.Pages.AddPage("item", tree, true, true)
+	j.App.SetFocus(tree)
+}
+
+// Columns for rendering value as a table: the keys that hold a scalar in at
+// least one element. Values that are not arrays of objects or scalars have no
+// columns.
+func tableColumns(value interface{}) ([]interface{}, []string) {
+	rows, ok := value.([]interface{})
+	if !ok || len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := []string{}
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		switch r := row.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(r))
+			for k := range r {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if !seen[k] && isJSONScalar(r[k]) {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		case []interface{}:
+			return nil, nil
+		default:
+			if !seen["value"] {
+				seen["value"] = true
+				columns = append(columns, "value")
+			}
+		}
+	}
+
+	// Names and ids identify a row, keep them on the left
+	sort.SliceStable(columns, func(a, b int) bool {
+		return columnRank(columns[a]) < columnRank(columns[b])
+	})
+
+	return append([]interface{}{}, rows...), columns
+}
+
+func columnRank(column string) int {
+	switch column {
+	case "name":
+		return 0
+	case "resourceGroup":
+		return 1
+	case "location":
+		return 2
+	case "id":
+		return 4
+	}
+	return 3
+}
+
+func jsonField(row interface{}, key string) interface{} {
+	if m, ok := row.(map[string]interface{}); ok {
+		return m[key]
+	}
+	if key == "value" {
+		return row
+	}
+	return nil
+}
+
+func isJSONScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+func jsonScalarText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}:
+		return fmt.Sprintf("{%v}", len(v))
+	case []interface{}:
+		return fmt.Sprintf("[%v]", len(v))
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// Numbers compare numerically, everything else case-insensitively as text.
+func compareJSONValues(a, b interface{}) int {
+	if an, ok := a.(json.Number); ok {
+		if bn, ok := b.(json.Number); ok {
+			af, aerr := strconv.ParseFloat(an.String(), 64)
+			bf, berr := strconv.ParseFloat(bn.String(), 64)
+			if aerr == nil && berr == nil {
+				switch {
+				case af < bf:
+					return -1
+				case af > bf:
+					return 1
+				}
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(strings.ToLower(jsonScalarText(a)), strings.ToLower(jsonScalarText(b)))
+}
+
+func newJSONTree(title string, value interface{}) *tview.TreeView {
+	root := newJSONTreeNode(title, value, 0)
+	root.SetExpanded(true)
+
+	tree := tview.NewTreeView().
+		SetRoot(root).
+		SetCurrentNode(root)
+	tree.SetTitle(tview.Escape(title))
+	tree.SetBorder(true)
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		node.SetExpanded(!node.IsExpanded())
+	})
+
+	return tree
+}
+
+// Only the first level below the root is expanded initially, deeper levels
+// are expanded on demand with Enter.
+func newJSONTreeNode(key string, value interface{}, depth int) *tview.TreeNode {
+	var node *tview.TreeNode
+	switch v := value.(type) {
+	case map[string]interface{}:
+		node = tview.NewTreeNode(tview.Escape(fmt.Sprintf("%v {%v}", key, len(v))))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			node.AddChild(newJSONTreeNode(k, v[k], depth+1))
+		}
+	case []interface{}:
+		node = tview.NewTreeNode(tview.Escape(fmt.Sprintf("%v [%v]", key, len(v))))
+		for i, item := range v {
+			node.AddChild(newJSONTreeNode(fmt.Sprintf("[%v]", i), item, depth+1))
+		}
+	default:
+		return tview.NewTreeNode(tview.Escape(fmt.Sprintf("%v: %v", key, jsonScalarText(v)))).
+			SetSelectable(true)
+	}
+
+	node.SetColor(tview.Styles.SecondaryTextColor)
+	node.SetExpanded(depth < 1)
+	return node
+}
@@ -5,23 +5,38 @@ import (
 	"strings"
 	"time"
 
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/azclient"
 	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/dryrun"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
 var appFuncMap = map[string]func(*AppLayout) tview.Primitive{
-	"Quit":            (*AppLayout).Quit,
-	"FocusView0":      (*AppLayout).FocusView0,
-	"FocusView1":      (*AppLayout).FocusView1,
-	"FocusView2":      (*AppLayout).FocusView2,
-	"FocusView3":      (*AppLayout).FocusView3,
-	"FocusView4":      (*AppLayout).FocusView4,
-	"FocusInputField": (*AppLayout).FocusInputField,
+	"Quit":              (*AppLayout).Quit,
+	"FocusView0":        (*AppLayout).FocusView0,
+	"FocusView1":        (*AppLayout).FocusView1,
+	"FocusView2":        (*AppLayout).FocusView2,
+	"FocusView3":        (*AppLayout).FocusView3,
+	"FocusView4":        (*AppLayout).FocusView4,
+	"FocusInputField":   (*AppLayout).FocusInputField,
+	"SpawnHistoryView":  (*AppLayout).SpawnHistoryView,
+	"ToggleDryRun":      (*AppLayout).ToggleDryRun,
+	"SwitchProfile":     (*AppLayout).SwitchProfile,
+	"ToggleLogView":     (*AppLayout).ToggleLogView,
+	"SpawnRequestsView": (*AppLayout).SpawnRequestsView,
+}
+
+func init() {
+	// ReloadConfig validates against ConfigSchema, which lists appFuncMap,
+	// so it can't be part of the map's initializer
+	appFuncMap["ReloadConfig"] = (*AppLayout).ReloadConfig
 }
 
 type AppLayout struct {
 	App              *tview.Application
+	Pages            *tview.Pages
 	Grid             *tview.Grid
 	Layout           *tview.Flex
 	InputField       *tview.InputField
@@ -29,9 +44,23 @@ type AppLayout struct {
 	ActionBar        *tview.TextView
 	statusBar        *tview.TextView
 	FocusedViewIndex int
+	modalFocus       map[string]tview.Primitive
+	historyView      *HistoryListView
+	requestsView     *RequestsListView
+	configPath       string
+	// Keys typed so far of a key sequence
+	pendingKeys string
+	// Cancels the sign-in in progress, see login
+	cancelLogin func()
+	// Layout with the log view below it, when shown
+	body    *tview.Flex
+	logView *LogView
+	// Listings of the list views by list, see autoRefresh
+	listings map[*tview.List]*listing
 }
 
 func NewAppLayout() *AppLayout {
+	applyTheme()
 	a := AppLayout{
 		App: tview.NewApplication(),
 		Grid: tview.NewGrid().
@@ -39,27 +68,48 @@ func NewAppLayout() *AppLayout {
 			SetRows(1, 1, -6, 1, 1).
 			SetBorders(true),
 		Layout:           tview.NewFlex(),
+		body:             tview.NewFlex(),
 		InputField:       tview.NewInputField().SetLabel("Search:"),
-		titleBar:         tview.NewTextView().SetLabel("aztui"),
+		titleBar:         tview.NewTextView().SetLabel("aztui").SetDynamicColors(true),
 		ActionBar:        tview.NewTextView().SetLabel(""),
-		statusBar:        tview.NewTextView().SetLabel(""),
+		statusBar:        tview.NewTextView().SetLabel("").SetDynamicColors(true),
 		FocusedViewIndex: 0,
+		Pages:            tview.NewPages(),
+		modalFocus:       make(map[string]tview.Primitive),
+		listings:         make(map[*tview.List]*listing),
 	}
+	a.logView = NewLogView(&a)
 
 	go func() {
 		for {
 			time.Sleep(1 * time.Second)
-			a.statusBar.SetText(fmt.Sprintf("Status Bar: %v", time.Now().Format("15:04:05")))
-			a.App.Draw()
+			a.App.QueueUpdateDraw(func() {
+				a.updateStatusBar()
+				a.autoRefresh()
+			})
 		}
 	}()
+	showPendingKeys = func(keys string) {
+		a.pendingKeys = keys
+		a.updateStatusBar()
+	}
+	queueUpdateDraw = func(f func()) {
+		a.App.QueueUpdateDraw(f)
+	}
 
 	a.Grid.AddItem(a.titleBar, 0, 0, 1, 4, 0, 100, false).
 		AddItem(a.InputField, 1, 0, 1, 4, 0, 100, true).
-		AddItem(a.Layout, 2, 0, 1, 4, 0, 100, false).
+		AddItem(a.body, 2, 0, 1, 4, 0, 100, false).
 		AddItem(a.statusBar, 3, 0, 1, 4, 0, 100, false).
 		AddItem(a.ActionBar, 4, 0, 1, 4, 0, 100, false)
 	a.Layout.SetDirection(tview.FlexColumn)
+	a.body.SetDirection(tview.FlexRow).
+		AddItem(a.Layout, 0, 3, false)
+	a.Pages.AddPage("main", a.Grid, true, true)
+	a.restyle()
+	dryrun.SetConfirmFunc(a.confirmPreview)
+	azcli.SetHistoryFunc(a.historyRecorded)
+	azclient.SetDeviceCodeFunc(a.showDeviceCode)
 	InitViewKeyBindings(&a)
 	a.UpdateActionBar(a.ActionBar)
 	return &a
@@ -67,7 +117,7 @@ func NewAppLayout() *AppLayout {
 
 func (a *AppLayout) UpdateActionBar(t *tview.TextView) {
 	actionBarText := ""
-	for _, view := range config.GConfig.Views {
+	for _, view := range config.Current().Views {
 		if view.Name == a.Name() {
 			for _, action := range view.Actions {
 				// Get the action name
@@ -77,7 +127,7 @@ func (a *AppLayout) UpdateActionBar(t *tview.TextView) {
 					actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
 				}
 			}
-			actionBarText = actionBarText[:len(actionBarText)-3] // Remove the last " | "
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
 			break
 		}
 	}
@@ -85,12 +135,70 @@ func (a *AppLayout) UpdateActionBar(t *tview.TextView) {
 	t.SetText(actionBarText)
 }
 
+func (a *AppLayout) updateStatusBar() {
+	status := fmt.Sprintf("Status Bar: %v", time.Now().Format("15:04:05"))
+	if a.pendingKeys != "" {
+		status += fmt.Sprintf(" | Keys: %v ...", tview.Escape(a.pendingKeys))
+	}
+
+	rateLimit := azclient.CurrentRateLimit()
+	if wait := time.Until(rateLimit.ThrottledUntil); wait > 0 {
+		status += " | " + errorText(fmt.Sprintf("Throttled, retrying in %v", wait.Round(time.Second)))
+	} else if rateLimit.RemainingReads >= 0 {
+		reads := fmt.Sprintf("ARM reads left: %v", rateLimit.RemainingReads)
+		if rateLimit.RemainingReads < config.Current().ARM.LowRateLimit {
+			reads = warningText(reads)
+		}
+		status += " | " + reads
+	}
+	a.statusBar.SetText(status)
+}
+
+// autoRefresh fetches the listings of the shown views again once their
+// refresh interval has passed, and forgets the ones of closed views.
+func (a *AppLayout) autoRefresh() {
+	for list, l := range a.listings {
+		if a.IndexOf(list) == -1 {
+			delete(a.listings, list)
+			continue
+		}
+
+		interval := l.refreshInterval()
+		if interval > 0 && !l.fetching && time.Since(l.loaded) >= interval {
+			l.revalidate()
+		}
+	}
+}
+
+// The title bar shows the active profile and the dry-run mode
+func (a *AppLayout) updateTitleBar() {
+	title := ""
+	if profile := config.ActiveProfile().Name; profile != "" {
+		title += " profile: " + tview.Escape(profile)
+	}
+
+	switch dryrun.CurrentMode() {
+	case dryrun.Preview:
+		title += " " + warningText("(dry run: preview before executing)")
+	case dryrun.Strict:
+		title += " " + errorText("(dry run: nothing is executed)")
+	}
+
+	a.titleBar.SetText(title)
+}
+
 func (a *AppLayout) Name() string {
 	return "AppLayout"
 }
 
 func (a *AppLayout) SetInputCapture(f func(event *tcell.EventKey) *tcell.EventKey) {
-	a.App.SetInputCapture(f)
+	a.App.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Application wide bindings would steal keys typed into a modal
+		if a.HasModal() {
+			return event
+		}
+		return f(event)
+	})
 }
 
 func (a *AppLayout) CustomInputHandler() func(event *tcell.EventKey) *tcell.EventKey {
@@ -105,6 +213,7 @@ func (a *AppLayout) CallAction(action string) (tview.Primitive, error) {
 }
 
 func (a *AppLayout) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
+	styleView(p)
 	a.Layout.AddItem(p, 0, width, takeFocus)
 	if takeFocus {
 		a.App.SetFocus(p)
@@ -148,6 +257,28 @@ func (a *AppLayout) FocusInputField() tview.Primitive {
 	return nil
 }
 
+// SpawnHistoryView opens the command history, or focuses it when it is
+// already open.
+func (a *AppLayout) SpawnHistoryView() tview.Primitive {
+	if a.historyView != nil && a.IndexOf(a.historyView.List) != -1 {
+		a.App.SetFocus(a.historyView.List)
+		return nil
+	}
+
+	a.historyView = NewHistoryListView(a)
+	return a.historyView.List
+}
+
+// historyRecorded lists entry in the command history when it is open. It is
+// called from the goroutine recording the command, see azcli.SetHistoryFunc.
+func (a *AppLayout) historyRecorded(entry azcli.HistoryEntry) {
+	go queueUpdateDraw(func() {
+		if a.historyView != nil {
+			a.historyView.add(entry)
+		}
+	})
+}
+
 func (a *AppLayout) Quit() tview.Primitive {
 	a.App.Stop()
 	return nil
@@ -175,6 +306,38 @@ func (a *AppLayout) Update() error {
 	return nil
 }
 
+// ReplaceView removes old, if it is still in the layout, and appends p.
+func (a *AppLayout) ReplaceView(old tview.Primitive, p tview.Primitive, takeFocus bool, width int) {
+	if old != nil {
+		a.Layout.RemoveItem(old)
+	}
+	a.AppendPrimitiveView(p, takeFocus, width)
+}
+
+// IndexOf returns the position of p in the layout, or -1.
+func (a *AppLayout) IndexOf(p tview.Primitive) int {
+	for i := 0; i < a.Layout.GetItemCount(); i++ {
+		if a.Layout.GetItem(i) == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// RemoveViewsAfter removes every view to the right of p, leaving focus
+// where it is.
+func (a *AppLayout) RemoveViewsAfter(p tview.Primitive) {
+	i := a.IndexOf(p)
+	if i == -1 {
+		return
+	}
+
+	for a.Layout.GetItemCount() > i+1 {
+		a.Layout.RemoveItem(a.Layout.GetItem(i + 1))
+	}
+	a.FocusedViewIndex = i
+}
+
 // index : index of a first view that should be removed
 func (a *AppLayout) RemoveViews(index int) {
 	itemCount := a.Layout.GetItemCount()
@@ -185,3 +348,45 @@ func (a *AppLayout) RemoveViews(index int) {
 	a.FocusedViewIndex = 0
 	a.App.SetFocus(a.Layout.GetItem(0))
 }
+
+// ShowModal displays p centered on top of the layout and gives it focus
+// until HideModal is called with the same name. Primitives that center
+// themselves, such as tview.Modal, are shown with a zero width and height.
+func (a *AppLayout) ShowModal(name string, p tview.Primitive, width, height int) {
+	var modal tview.Primitive = p
+	if width > 0 && height > 0 {
+		modal = centered(p, width, height)
+	}
+
+	if _, ok := a.modalFocus[name]; !ok {
+		a.modalFocus[name] = a.App.GetFocus()
+	}
+	a.Pages.AddPage(name, modal, true, true)
+	a.App.SetFocus(p)
+}
+
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
+
+// HideModal removes a modal shown with ShowModal and returns focus to
+// whatever had it before the modal was shown.
+func (a *AppLayout) HideModal(name string) {
+	a.Pages.RemovePage(name)
+	if p, ok := a.modalFocus[name]; ok {
+		delete(a.modalFocus, name)
+		if p != nil {
+			a.App.SetFocus(p)
+		}
+	}
+}
+
+func (a *AppLayout) HasModal() bool {
+	return a.Pages.GetPageCount() > 1
+}
@@ -0,0 +1,67 @@
+package resourceviews
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/rivo/tview"
+)
+
+func TestUpdateActionBarWithoutActions(t *testing.T) {
+	views := []interface {
+		Name() string
+		UpdateActionBar(*tview.TextView)
+	}{
+		&AppLayout{},
+		&SubscriptionListView{},
+		&ResourceGroupListView{},
+		&ResourceTypeListView{},
+		&ResourceListView{},
+		&AKSClusterListView{},
+		&VirtualMachineListView{},
+		&BulkRunView{},
+		&CommandOutputView{},
+		&ResourceDetailView{},
+		&HistoryListView{},
+		&LogView{},
+		&RequestsListView{},
+	}
+
+	if _, err := config.LoadConfig(""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.LoadConfig("") })
+
+	// Remove every action of every view
+	data := "views:\n"
+	for _, view := range config.Current().Views {
+		data += fmt.Sprintf("  - view: %v\n    actions:\n", view.Name)
+		for _, action := range view.Actions {
+			data += fmt.Sprintf("      - action: %v\n        remove: true\n", action.Action)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "aztui.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, view := range views {
+		for _, v := range config.Current().Views {
+			if v.Name == view.Name() && len(v.Actions) != 0 {
+				t.Errorf("%v still has %v actions", view.Name(), len(v.Actions))
+			}
+		}
+
+		actionBar := tview.NewTextView()
+		view.UpdateActionBar(actionBar)
+		if text := actionBar.GetText(false); text != "" {
+			t.Errorf("%v action bar = %q, want it empty", view.Name(), text)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package resourceviews
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/cache"
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/rivo/tview"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+)
+
+// listItem is a row of a list view, as fetched and cached.
+type listItem struct {
+	Name      string `json:"name"`
+	Secondary string `json:"secondary,omitempty"`
+	// Resource ID, or whatever else identifies the row
+	ID string `json:"id,omitempty"`
+	// Power state of a virtual machine, e.g. "running"
+	State string `json:"state,omitempty"`
+	// Name of the subscription of the row, in views across subscriptions
+	Subscription string `json:"subscription,omitempty"`
+	// Why the subscription of the row couldn't be listed, see fanOut
+	Err string `json:"err,omitempty"`
+}
+
+// Actions of every list view backed by a listing, see callAction
+var listingFuncMap = map[string]func(*listing) tview.Primitive{
+	"ToggleSelection":   (*listing).ToggleSelection,
+	"SelectAllMatching": (*listing).SelectAllMatching,
+	"InvertSelection":   (*listing).InvertSelection,
+}
+
+// How long added, changed and removed rows stay highlighted after a refresh
+const highlightDuration = 5 * time.Second
+
+// change is how a row differs from the one shown before a refresh.
+type change int
+
+const (
+	added change = iota + 1
+	changed
+	removed
+)
+
+// listing holds the items of a list view. Items are shown from the cache at
+// once, marked stale, and fetched again in the background, see load.
+type listing struct {
+	list  *tview.List
+	title string
+	key   cache.Key
+	// Name of the view, whose refresh interval applies, see autoRefresh
+	view string
+	// Fetches the items, called off the UI goroutine
+	fetch func() ([]listItem, error)
+	// Shows the items in the list, e.g. the ones matching the search text
+	render func()
+
+	items []listItem
+	// When items were fetched, zero before they first are
+	fetched time.Time
+	// Whether items are from the cache or a previous load and are being
+	// fetched again
+	stale bool
+	// Error of the last fetch
+	err error
+	// Incremented by every load, so only the latest fetch is applied
+	generation int
+	// When the last fetch started, and whether it is still running
+	loaded   time.Time
+	fetching bool
+
+	// Rows shown, by index, with zero items for placeholders
+	shown []listItem
+	// Rows highlighted since the last refresh by identity, and the removed
+	// ones still shown
+	changes  map[string]change
+	removed  []listItem
+	diffSeen int
+	// Lowercase search text the shown rows are filtered with, see matching
+	filter string
+	// Identity of the row last selected, kept while it is filtered out
+	selection string
+	// Set while show fills the list, so its changes aren't taken for the
+	// user's
+	rendering bool
+	// Identities of the rows selected for actions on several rows, see
+	// marked
+	marks map[string]bool
+}
+
+// newListing makes the listing of the list of view, refreshed in the
+// background while it is shown when the view has a refresh interval.
+func newListing(parent *AppLayout, view string, list *tview.List, title string, key cache.Key, fetch func() ([]listItem, error)) *listing {
+	l := &listing{
+		list:  list,
+		title: title,
+		key:   key,
+		view:  view,
+		fetch: fetch,
+	}
+	l.render = func() {
+		l.show(l.items, "")
+	}
+	list.SetChangedFunc(func(index int, _, _ string, _ rune) {
+		if !l.rendering && index < len(l.shown) {
+			l.selection = l.shown[index].identity()
+		}
+	})
+	parent.listings[list] = l
+	return l
+}
+
+// listingKey returns the cache key of a listing of the active profile.
+func listingKey(subscriptionID, scope, listingType string) cache.Key {
+	profile := config.ActiveProfile()
+	return cache.Key{
+		Tenant:       profile.Name + "/" + profile.Tenant,
+		Subscription: subscriptionID,
+		Scope:        scope,
+		Type:         listingType,
+	}
+}
+
+// load shows the items at hand, from the cache when there are none yet, and
+// fetches them again in the background. Must be called on the UI goroutine.
+func (l *listing) load() {
+	if l.items == nil {
+		var cached []listItem
+		if fetched, ok := cache.Load(l.key, &cached); ok {
+			l.items, l.fetched = cached, fetched
+		}
+	}
+	l.stale = true
+	l.err = nil
+	l.render()
+	l.updateTitle()
+	l.revalidate()
+}
+
+// revalidate fetches the items again in the background, highlighting what
+// changed once they are shown. Must be called on the UI goroutine.
+func (l *listing) revalidate() {
+	l.generation++
+	generation := l.generation
+	l.loaded = time.Now()
+	l.fetching = true
+
+	go func() {
+		items, err := l.fetch()
+		if err == nil {
+			if err := cache.Store(l.key, items); err != nil {
+				logger.Warn("Failed to cache listing", "type", l.key.Type, "err", err)
+			}
+		} else {
+			logger.Error("Failed to list", "type", l.key.Type, "subscription", l.key.Subscription, "scope", l.key.Scope, "err", err)
+		}
+
+		queueUpdateDraw(func() {
+			if generation != l.generation {
+				return
+			}
+
+			l.fetching = false
+			l.stale = false
+			l.err = err
+			if err == nil {
+				if l.items != nil {
+					l.diff(items)
+				}
+				l.items, l.fetched = items, time.Now()
+			}
+			l.render()
+			l.updateTitle()
+		})
+	}()
+}
+
+// refreshInterval returns the refresh interval configured for the view, zero
+// when it isn't refreshed in the background.
+func (l *listing) refreshInterval() time.Duration {
+	for _, view := range config.Current().Views {
+		if view.Name == l.view {
+			return view.RefreshInterval
+		}
+	}
+	return 0
+}
+
+// failedSubscriptions returns how many subscriptions of a listing across
+// subscriptions couldn't be listed.
+func (l *listing) failedSubscriptions() int {
+	failed := 0
+	for _, item := range l.items {
+		if item.Err != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// scope returns the subscription, resource group and type of the resource
+// ID of item, empty when it isn't one.
+func (item listItem) scope() (subscriptionID, resourceGroup, resourceType string) {
+	id, err := arm.ParseResourceID(item.ID)
+	if err != nil {
+		return "", "", ""
+	}
+	return id.SubscriptionID, id.ResourceGroupName, id.ResourceType.String()
+}
+
+// identity returns what identifies item across refreshes.
+func (item listItem) identity() string {
+	if item.ID != "" {
+		return strings.ToLower(item.ID)
+	}
+	return item.Name
+}
+
+// diff highlights the rows of items that were added or changed since the
+// shown ones, and keeps showing the removed ones, for a while.
+func (l *listing) diff(items []listItem) {
+	previous := map[string]listItem{}
+	for _, item := range l.items {
+		previous[item.identity()] = item
+	}
+
+	changes := map[string]change{}
+	for _, item := range items {
+		old, ok := previous[item.identity()]
+		switch {
+		case !ok:
+			changes[item.identity()] = added
+		case old != item:
+			changes[item.identity()] = changed
+		}
+		delete(previous, item.identity())
+	}
+	var gone []listItem
+	for _, item := range l.items {
+		if _, ok := previous[item.identity()]; ok {
+			changes[item.identity()] = removed
+			gone = append(gone, item)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	l.changes, l.removed = changes, gone
+	l.diffSeen++
+	seen := l.diffSeen
+	time.AfterFunc(highlightDuration, func() {
+		queueUpdateDraw(func() {
+			// A later refresh highlights its own changes
+			if seen != l.diffSeen {
+				return
+			}
+			l.changes, l.removed = nil, nil
+			l.render()
+		})
+	})
+}
+
+// updateTitle shows the age of stale items, and whether fetching them
+// failed.
+func (l *listing) updateTitle() {
+	title := l.title
+	switch {
+	case l.err != nil && l.fetched.IsZero():
+		title += " " + errorText("[failed]")
+	case l.err != nil:
+		title += " " + errorText(fmt.Sprintf("[stale, %v old, refresh failed]", age(time.Since(l.fetched))))
+	case l.stale && l.fetched.IsZero():
+		title += " [loading]"
+	case l.stale:
+		title += " " + warningText(fmt.Sprintf("[stale, %v old]", age(time.Since(l.fetched))))
+	}
+	if failed := l.failedSubscriptions(); failed > 0 {
+		title += " " + errorText(fmt.Sprintf("[failed subscriptions: %v]", failed))
+	}
+	if marked := len(l.marked()); marked > 0 {
+		title += fmt.Sprintf(" [%v selected]", marked)
+	}
+	l.list.SetTitle(title)
+}
+
+// show lists items, or empty when there are none once they are fetched,
+// keeping the selected row selected and the list scrolled where it was. A
+// row filtered out is selected again once it is shown. It reports whether
+// the selected row was kept.
+func (l *listing) show(items []listItem, empty string) bool {
+	// The first row is selected without the user moving to it
+	if item, ok := l.current(); ok && l.selection == "" {
+		l.selection = item.identity()
+	}
+	offset, _ := l.list.GetOffset()
+	l.rendering = true
+	defer func() {
+		l.rendering = false
+	}()
+
+	l.list.Clear()
+	l.shown = []listItem{}
+	// Removed rows are shown after the current ones until their highlight
+	// expires, unless filtered out with the rest
+	rows := append(append([]listItem{}, items...), l.removedOf()...)
+	for _, item := range rows {
+		name := item.Name
+		secondary := item.Secondary
+		if item.State != "" {
+			secondary += " - " + powerStateText(item.State)
+		}
+		if item.Subscription != "" {
+			secondary = strings.TrimSuffix(tview.Escape(item.Subscription)+" | "+secondary, " | ")
+		}
+		if item.Err != "" {
+			name = errorText(tview.Escape(name))
+		}
+		if l.marks[item.identity()] {
+			name = "* " + name
+		}
+		switch l.changes[item.identity()] {
+		case added:
+			name = successText(name)
+		case changed:
+			name = warningText(name)
+		case removed:
+			name = errorText(name)
+			secondary += " (removed)"
+		}
+		l.list.AddItem(name, secondary, 0, nil)
+		l.shown = append(l.shown, item)
+	}
+
+	switch {
+	case len(items) > 0:
+	case l.err != nil && l.fetched.IsZero():
+		l.list.AddItem(errorText(tview.Escape(fmt.Sprintf("(%v)", l.err))), "", 0, nil)
+	case empty != "" && !l.fetched.IsZero():
+		l.list.AddItem(empty, "", 0, nil)
+	}
+
+	if l.selection == "" {
+		return false
+	}
+	l.list.SetOffset(offset, 0)
+	for i, item := range l.shown {
+		if item.identity() == l.selection {
+			l.list.SetCurrentItem(i)
+			return true
+		}
+	}
+	return false
+}
+
+// matching returns the items whose name contains filter, ignoring case.
+// Removed rows still shown are filtered alike.
+func (l *listing) matching(filter string) []listItem {
+	l.filter = strings.ToLower(filter)
+	items := []listItem{}
+	for _, item := range l.items {
+		if strings.Contains(strings.ToLower(item.Name), l.filter) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// removedOf returns the removed rows still shown that match the filter.
+func (l *listing) removedOf() []listItem {
+	items := []listItem{}
+	for _, item := range l.removed {
+		if strings.Contains(strings.ToLower(item.Name), l.filter) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// current returns the item of the selected row, false for placeholders.
+func (l *listing) current() (listItem, bool) {
+	current := l.list.GetCurrentItem()
+	if current < 0 || current >= len(l.shown) || l.list.GetItemCount() == 0 {
+		return listItem{}, false
+	}
+	return l.shown[current], true
+}
+
+// selected returns the item of the selected row, false for placeholders,
+// subscriptions that couldn't be listed and removed rows, which there is
+// nothing to act on.
+func (l *listing) selected() (listItem, bool) {
+	item, ok := l.current()
+	if !ok || item.Err != "" || l.changes[item.identity()] == removed {
+		return listItem{}, false
+	}
+	return item, true
+}
+
+// callAction runs the listing action named action, for the CallAction of
+// the views backed by a listing.
+func (l *listing) callAction(action string) (tview.Primitive, error) {
+	if actionFunc, ok := listingFuncMap[action]; ok {
+		return actionFunc(l), nil
+	}
+	return nil, fmt.Errorf("no action for %s", action)
+}
+
+// marked returns the selected rows, in the order they are listed. Rows
+// removed since they were selected are left out.
+func (l *listing) marked() []listItem {
+	items := []listItem{}
+	for _, item := range l.items {
+		if l.marks[item.identity()] && item.Err == "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// targets returns the rows action acts on: the selected ones when there are
+// any and the action is configured with multiple: true, otherwise the
+// current row, if any.
+func (l *listing) targets(action string) []listItem {
+	if marked := l.marked(); len(marked) > 0 && multipleTargets(l.view, action) {
+		return marked
+	}
+	if item, ok := l.selected(); ok {
+		return []listItem{item}
+	}
+	return nil
+}
+
+// multipleTargets reports whether action of view is configured to act on
+// every selected row.
+func multipleTargets(view, action string) bool {
+	for _, v := range config.Current().Views {
+		if v.Name != view {
+			continue
+		}
+		for _, a := range v.Actions {
+			if a.Action == action {
+				return a.Multiple
+			}
+		}
+	}
+	return false
+}
+
+// mark selects or deselects the rows of items, keeping the current row.
+func (l *listing) mark(items []listItem, marked func(listItem) bool) {
+	if l.marks == nil {
+		l.marks = map[string]bool{}
+	}
+	for _, item := range items {
+		if item.Err != "" || l.changes[item.identity()] == removed {
+			continue
+		}
+		if marked(item) {
+			l.marks[item.identity()] = true
+		} else {
+			delete(l.marks, item.identity())
+		}
+	}
+	l.render()
+	l.updateTitle()
+}
+
+// ToggleSelection selects or deselects the current row and moves on to the
+// next one.
+func (l *listing) ToggleSelection() tview.Primitive {
+	item, ok := l.selected()
+	if !ok {
+		return nil
+	}
+	l.mark([]listItem{item}, func(item listItem) bool {
+		return !l.marks[item.identity()]
+	})
+	if next := l.list.GetCurrentItem() + 1; next < l.list.GetItemCount() {
+		l.list.SetCurrentItem(next)
+	}
+	return nil
+}
+
+// SelectAllMatching selects every row matching the search text.
+func (l *listing) SelectAllMatching() tview.Primitive {
+	l.mark(l.shown, func(listItem) bool {
+		return true
+	})
+	return nil
+}
+
+// InvertSelection selects the rows matching the search text that aren't
+// selected, and deselects the ones that are.
+func (l *listing) InvertSelection() tview.Primitive {
+	l.mark(l.shown, func(item listItem) bool {
+		return !l.marks[item.identity()]
+	})
+	return nil
+}
+
+// age formats how old a listing is, e.g. "5m".
+func age(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%vs", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%vm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%vh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%vd", int(d.Hours()/24))
+}
@@ -0,0 +1,107 @@
+package resourceviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brendank310/aztui/pkg/azclient"
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/rivo/tview"
+)
+
+const loginModal = "login"
+
+// ResetViews closes every view and lists the subscriptions of the active
+// profile again, for instance after the profile changed. Credentials that
+// ask the user to sign in are signed in to first.
+func (a *AppLayout) ResetViews() {
+	for a.Layout.GetItemCount() > 0 {
+		a.Layout.RemoveItem(a.Layout.GetItem(0))
+	}
+	a.FocusedViewIndex = 0
+	a.updateTitleBar()
+
+	if azclient.NeedsLogin() {
+		a.login()
+		return
+	}
+
+	NewSubscriptionListView(a)
+}
+
+// login signs in to the credential of the active profile in the background,
+// so the device code can be shown while the sign-in waits for the user, and
+// lists the subscriptions once signed in.
+func (a *AppLayout) login() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancelLogin = cancel
+	profile := config.ActiveProfile()
+	message := "Signing in"
+	if profile.Name != "" {
+		message += " to profile " + profile.Name
+	}
+	if strings.EqualFold(profile.Credential, "browser") {
+		message += ", complete the sign-in in the browser"
+	}
+	a.showLogin(message + "...")
+
+	go func() {
+		err := azclient.Login(ctx)
+		a.App.QueueUpdateDraw(func() {
+			a.cancelLogin = nil
+			a.HideModal(loginModal)
+			if err != nil {
+				logger.Error("Sign-in failed", "profile", config.ActiveProfile().Name, "err", err)
+				a.showLoginFailed(ctx, err)
+				return
+			}
+
+			NewSubscriptionListView(a)
+		})
+	}()
+}
+
+// showDeviceCode shows the instructions of the device code flow, see
+// azclient.SetDeviceCodeFunc.
+func (a *AppLayout) showDeviceCode(message string) {
+	a.App.QueueUpdateDraw(func() {
+		if a.cancelLogin != nil {
+			a.showLogin(message)
+		}
+	})
+}
+
+func (a *AppLayout) showLogin(message string) {
+	modal := tview.NewModal().
+		SetText(tview.Escape(message)).
+		AddButtons([]string{"Cancel"}).
+		SetDoneFunc(func(int, string) {
+			if a.cancelLogin != nil {
+				a.cancelLogin()
+			}
+		})
+
+	a.ShowModal(loginModal, modal, 0, 0)
+}
+
+func (a *AppLayout) showLoginFailed(ctx context.Context, err error) {
+	text := fmt.Sprintf("Sign-in failed:\n\n%v", err)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		text = "Sign-in canceled."
+	}
+
+	modal := tview.NewModal().
+		SetText(tview.Escape(text)).
+		AddButtons([]string{"Retry", "Close"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.HideModal(loginModal)
+			if label == "Retry" {
+				a.login()
+			}
+		})
+
+	a.ShowModal(loginModal, modal, 0, 0)
+}
@@ -0,0 +1,220 @@
+package resourceviews
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const logSearchModal = "logsearch"
+
+var logViewFuncMap = map[string]func(*LogView) tview.Primitive{
+	"CycleLogLevel": (*LogView).CycleLogLevel,
+	"SearchLog":     (*LogView).SearchLog,
+	"CloseLogView":  (*LogView).CloseLogView,
+}
+
+// The levels CycleLogLevel steps through
+var logLevels = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// LogView shows the recent entries of the log as they are logged, below the
+// other views. It is shown and hidden with ToggleLogView.
+type LogView struct {
+	TextView *tview.TextView
+	Parent   *AppLayout
+	// Entries below this level are hidden
+	level slog.Level
+	// Only entries containing this are shown, ignoring case
+	search string
+	shown  bool
+	// Set while a refresh for newly logged entries is queued
+	queued atomic.Bool
+}
+
+func NewLogView(layout *AppLayout) *LogView {
+	l := LogView{
+		TextView: tview.NewTextView(),
+		Parent:   layout,
+		level:    logger.Level(),
+	}
+
+	l.TextView.SetBorder(true)
+	l.TextView.SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false)
+
+	l.TextView.SetFocusFunc(func() {
+		InitViewKeyBindings(&l)
+		l.UpdateActionBar(l.Parent.ActionBar)
+	})
+
+	// Entries can be logged from any goroutine, including the UI goroutine
+	// while it handles an event, where queueing an update would wait for
+	// itself. Refreshes are queued from their own goroutine and coalesced.
+	logger.SetEntryFunc(func(logger.Entry) {
+		if l.queued.CompareAndSwap(false, true) {
+			go queueUpdateDraw(func() {
+				l.queued.Store(false)
+				l.Update()
+			})
+		}
+	})
+
+	return &l
+}
+
+func (l *LogView) Name() string {
+	return "LogView"
+}
+
+func (l *LogView) UpdateActionBar(t *tview.TextView) {
+	actionBarText := ""
+	for _, view := range config.Current().Views {
+		if view.Name == l.Name() {
+			for _, action := range view.Actions {
+				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
+			}
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
+			break
+		}
+	}
+
+	t.SetText(actionBarText)
+}
+
+func (l *LogView) SetInputCapture(f func(event *tcell.EventKey) *tcell.EventKey) {
+	l.TextView.SetInputCapture(f)
+}
+
+func (l *LogView) CustomInputHandler() func(event *tcell.EventKey) *tcell.EventKey {
+	return nil
+}
+
+func (l *LogView) CallAction(action string) (tview.Primitive, error) {
+	if actionFunc, ok := logViewFuncMap[action]; ok {
+		return actionFunc(l), nil
+	}
+	return nil, fmt.Errorf("no action for %s", action)
+}
+
+func (l *LogView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
+	l.Parent.AppendPrimitiveView(p, takeFocus, width)
+}
+
+// Update shows the recent entries at or above the level that contain the
+// search text. The view keeps following new entries unless it was scrolled
+// up.
+func (l *LogView) Update() error {
+	if !l.shown {
+		return nil
+	}
+
+	title := fmt.Sprintf("Log (%v and above", strings.ToLower(l.level.String()))
+	if l.search != "" {
+		title += ", search: " + tview.Escape(l.search)
+	}
+	l.TextView.SetTitle(title + ")")
+
+	search := strings.ToLower(l.search)
+	var text strings.Builder
+	for _, entry := range logger.Recent() {
+		if entry.Level < l.level {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(entry.String()), search) {
+			continue
+		}
+
+		level := fmt.Sprintf("%-5v", entry.Level)
+		switch {
+		case entry.Level >= slog.LevelError:
+			level = errorText(level)
+		case entry.Level >= slog.LevelWarn:
+			level = warningText(level)
+		case entry.Level < slog.LevelInfo:
+			level = colorize(theme.SecondaryText, level)
+		}
+		fmt.Fprintf(&text, "%v %v %v", entry.Time.Format("15:04:05.000"), level, tview.Escape(entry.Message))
+		if entry.Attrs != "" {
+			text.WriteString(" " + colorize(theme.SecondaryText, tview.Escape(entry.Attrs)))
+		}
+		text.WriteString("\n")
+	}
+	l.TextView.SetText(text.String())
+
+	return nil
+}
+
+// CycleLogLevel steps the lowest level shown through debug, info, warn and
+// error. Entries below the level of the log file aren't kept at all.
+func (l *LogView) CycleLogLevel() tview.Primitive {
+	next := logLevels[0]
+	for i, level := range logLevels {
+		if level == l.level && i+1 < len(logLevels) {
+			next = logLevels[i+1]
+		}
+	}
+	l.level = next
+	l.Update()
+	return nil
+}
+
+// SearchLog asks for the text entries have to contain to be shown. An empty
+// text shows every entry again.
+func (l *LogView) SearchLog() tview.Primitive {
+	field := tview.NewInputField().
+		SetLabel("Search: ").
+		SetText(l.search)
+	field.SetBorder(true)
+	field.SetTitle("Search Log")
+	styleView(field)
+	field.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			l.search = field.GetText()
+			l.Update()
+			l.TextView.ScrollToEnd()
+		}
+		l.Parent.HideModal(logSearchModal)
+	})
+
+	l.Parent.ShowModal(logSearchModal, field, 60, 3)
+	return nil
+}
+
+func (l *LogView) CloseLogView() tview.Primitive {
+	l.Parent.ToggleLogView()
+	return nil
+}
+
+// ToggleLogView shows the log below the other views and focuses it. When it
+// is already focused it is hidden, when it is shown but not focused it gets
+// the focus.
+func (a *AppLayout) ToggleLogView() tview.Primitive {
+	l := a.logView
+	switch {
+	case !l.shown:
+		l.shown = true
+		a.body.AddItem(l.TextView, 0, 1, false)
+		l.Update()
+		l.TextView.ScrollToEnd()
+		a.App.SetFocus(l.TextView)
+	case !l.TextView.HasFocus():
+		a.App.SetFocus(l.TextView)
+	default:
+		l.shown = false
+		a.body.RemoveItem(l.TextView)
+		a.FocusView(a.FocusedViewIndex)
+		if l.TextView.HasFocus() {
+			// No views to go back to, e.g. while signing in
+			a.App.SetFocus(a.Layout)
+			a.UpdateActionBar(a.ActionBar)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package resourceviews
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/azclient"
+	"github.com/brendank310/aztui/pkg/utils"
+	"github.com/rivo/tview"
+)
+
+const portalModal = "portal"
+
+// CopyPortalLink copies the links to targets in the portal of the active
+// profile's cloud, one per line. The links are shown instead when they can't
+// be copied.
+func (a *AppLayout) CopyPortalLink(targets ...azcli.Target) {
+	links := []string{}
+	var err error
+	for _, target := range targets {
+		var link string
+		link, err = azclient.PortalURL(target.ID())
+		if err != nil {
+			break
+		}
+		links = append(links, link)
+	}
+	link := strings.Join(links, "\n")
+	if err == nil {
+		err = utils.CopyToClipboard(link)
+	}
+	if err == nil {
+		return
+	}
+
+	text := fmt.Sprintf("Unable to copy the portal link: %v", err)
+	if link != "" {
+		text += "\n\n" + link
+	}
+	modal := tview.NewModal().
+		SetText(tview.Escape(text)).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(int, string) {
+			a.HideModal(portalModal)
+		})
+	a.ShowModal(portalModal, modal, 0, 0)
+}
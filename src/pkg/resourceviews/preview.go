@@ -0,0 +1,55 @@
+package resourceviews
+
+import (
+	"fmt"
+
+	"github.com/brendank310/aztui/pkg/dryrun"
+	"github.com/rivo/tview"
+)
+
+const previewModal = "preview"
+
+// ShowPreview shows req and asks whether it should be executed. Exactly one
+// of execute and cancel is called once the user decided, on the UI
+// goroutine, so they must not block: hand the answer to whoever waits for it,
+// e.g. through a buffered channel.
+func (a *AppLayout) ShowPreview(req dryrun.Request, execute func(), cancel func()) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("About to execute:\n\n%v", tview.Escape(req.String()))).
+		AddButtons([]string{"Cancel", "Execute"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.HideModal(previewModal)
+			if label == "Execute" {
+				execute()
+			} else {
+				cancel()
+			}
+		})
+
+	a.ShowModal(previewModal, modal, 0, 0)
+}
+
+// confirmPreview asks for confirmation of req, see dryrun.SetConfirmFunc.
+// It hands req to ShowPreview on the UI goroutine and waits for the answer,
+// so it must be called from another goroutine. ARM requests needing
+// confirmation are only issued from background goroutines.
+func (a *AppLayout) confirmPreview(req dryrun.Request) error {
+	answer := make(chan error, 1)
+	a.App.QueueUpdateDraw(func() {
+		a.ShowPreview(req, func() {
+			answer <- nil
+		}, func() {
+			answer <- dryrun.ErrDeclined
+		})
+	})
+
+	return <-answer
+}
+
+// ToggleDryRun cycles through executing everything, previewing mutating
+// commands and strict dry-run.
+func (a *AppLayout) ToggleDryRun() tview.Primitive {
+	dryrun.NextMode()
+	a.updateTitleBar()
+	return nil
+}
@@ -1,7 +1,8 @@
 package resourceviews
 
 import (
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/brendank310/aztui/pkg/config"
 	"github.com/brendank310/aztui/pkg/logger"
@@ -32,6 +33,14 @@ type PrimitiveView interface {
 	UpdateActionBar(actionBar *tview.TextView)
 }
 
+// Hooks into the AppLayout, which owns the status bar showing the keys typed
+// so far of a key sequence and the event loop key sequence timeouts are
+// handled on.
+var (
+	showPendingKeys = func(keys string) {}
+	queueUpdateDraw = func(f func()) {}
+)
+
 /**
  * InitKeyBindings initializes key bindings for a given layout.
  * The key bindings are based on the configuration file.
@@ -39,23 +48,36 @@ type PrimitiveView interface {
 func InitViewKeyBindings(view PrimitiveView) {
 	viewName := view.Name()
 
-	// find matching actions
-	actions := make([]config.Action, 0)
-	for _, view := range config.GConfig.Views {
-		if view.Name == viewName {
-			actions = view.Actions
-			break
-		}
+	if len(keyBindings(viewName)) == 0 {
+		logger.Debug("No actions configured", "view", viewName)
 	}
 
-	if len(actions) == 0 {
-		logger.Println("No actions found for", viewName)
+	// Keys typed so far of a sequence bound in the view, only used on the
+	// UI goroutine. generation changes whenever pending does, so a timeout
+	// only acts on the sequence it was started for.
+	var pending config.KeySequence
+	generation := 0
+	reset := func() {
+		if len(pending) > 0 {
+			showPendingKeys("")
+		}
+		pending = nil
+		generation++
 	}
 
-	// find matching key_mappings
-	keyActionMap := make(map[string]config.Action)
-	for _, action := range actions {
-		keyActionMap[action.Key] = action
+	fire := func(action config.Action) bool {
+		logger.Debug("Running action", "view", view.Name(), "action", action.Action, "key", action.Key)
+		// call the function with the action name
+		newView, err := view.CallAction(action.Action)
+		if err != nil {
+			logger.Error("Action failed", "view", view.Name(), "action", action.Action, "err", err)
+			return false
+		}
+
+		if newView != nil {
+			view.AppendPrimitiveView(newView, action.TakeFocus, action.Width)
+		}
+		return true
 	}
 
 	// set the input capture
@@ -69,22 +91,45 @@ func InitViewKeyBindings(view PrimitiveView) {
 			}
 		}
 
-		// check if the key is in the keyActionMap
-		keyName := event.Name()
-		keyName = strings.TrimSuffix(strings.TrimPrefix(keyName, "Rune["), "]")
-		logger.Println("Key pressed ", keyName)
+		stroke := config.KeyStrokeFromEvent(event)
+		logger.Debug("Key pressed", "view", viewName, "key", stroke.String())
+
+		bindings := keyBindings(viewName)
+		sequence := append(append(config.KeySequence{}, pending...), stroke)
+		match, longer := matchKeys(bindings, sequence)
+		if match == nil && !longer && len(pending) > 0 {
+			// The sequence typed so far went nowhere, start over with
+			// this key
+			reset()
+			sequence = config.KeySequence{stroke}
+			match, longer = matchKeys(bindings, sequence)
+		}
 
-		if action, exists := keyActionMap[keyName]; exists {
-			logger.Println("Action found for key", keyName, action.Action)
-			// call the function with the action name
-			newView, err := view.CallAction(action.Action)
-			if err != nil {
-				logger.Println("Error calling action", action.Action, err)
-				return event
-			}
+		switch {
+		case longer:
+			// Wait for the next key. When the sequence typed so far is
+			// bound itself, it fires if none follows in time.
+			reset()
+			pending = sequence
+			showPendingKeys(sequence.String())
+			started := generation
+			time.AfterFunc(config.KeyTimeout(), func() {
+				queueUpdateDraw(func() {
+					if generation != started {
+						return
+					}
+					reset()
+					if match != nil {
+						fire(*match)
+					}
+				})
+			})
+			return nil
 
-			if newView != nil {
-				view.AppendPrimitiveView(newView, action.TakeFocus, action.Width)
+		case match != nil:
+			reset()
+			if !fire(*match) {
+				return event
 			}
 			return nil
 		}
@@ -92,3 +137,106 @@ func InitViewKeyBindings(view PrimitiveView) {
 		return event
 	})
 }
+
+type keyBinding struct {
+	action   config.Action
+	sequence config.KeySequence
+}
+
+var (
+	bindingsMu sync.Mutex
+	// Configuration the bindings were parsed from, see keyBindings
+	bindingsConfig *config.Config
+	// Key bindings of every view by view name
+	bindingsByView map[string][]keyBinding
+)
+
+// keyBindings returns the key sequences bound in the view. They are parsed
+// once for every configuration loaded, so reloading it applies to views that
+// are already bound.
+func keyBindings(viewName string) []keyBinding {
+	current := config.Current()
+
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+	if current != bindingsConfig {
+		bindingsByView = parseKeyBindings(current)
+		bindingsConfig = current
+	}
+
+	return bindingsByView[viewName]
+}
+
+// parseKeyBindings returns the key bindings of every view of c. Bindings that
+// don't parse are skipped, they are reported by config validation.
+func parseKeyBindings(c *config.Config) map[string][]keyBinding {
+	views := map[string][]keyBinding{}
+	for _, view := range c.Views {
+		if _, ok := views[view.Name]; ok {
+			// The first configuration of a view applies
+			continue
+		}
+		bindings := []keyBinding{}
+		for _, action := range view.Actions {
+			sequence, err := config.ParseKeySequence(action.Key)
+			if err != nil {
+				logger.Warn("Invalid key binding", "view", view.Name, "action", action.Action, "err", err)
+				continue
+			}
+			bindings = append(bindings, keyBinding{action, sequence})
+		}
+		views[view.Name] = bindings
+	}
+
+	return views
+}
+
+// matchKeys returns the action bound to exactly the typed sequence, if any,
+// and whether a longer sequence starting with it is bound.
+func matchKeys(bindings []keyBinding, typed config.KeySequence) (*config.Action, bool) {
+	var match *config.Action
+	longer := false
+	for i := range bindings {
+		b := &bindings[i]
+		if !b.sequence.HasPrefix(typed) {
+			continue
+		}
+		if len(b.sequence) == len(typed) {
+			if match == nil {
+				match = &b.action
+			}
+		} else {
+			longer = true
+		}
+	}
+
+	return match, longer
+}
+
+// ConfigSchema returns the views that can be configured and the actions each
+// of them supports, for validating configuration files.
+func ConfigSchema() config.Schema {
+	return config.Schema{
+		config.AppLayoutView:     actionNames(appFuncMap),
+		"SubscriptionListView":   append(actionNames(subscriptionSelectItemFuncMap), actionNames(listingFuncMap)...),
+		"ResourceGroupListView":  append(actionNames(resourceGroupSelectItemFuncMap), actionNames(listingFuncMap)...),
+		"ResourceTypeListView":   append(actionNames(resourceTypeSelectItemFuncMap), actionNames(listingFuncMap)...),
+		"ResourceListView":       append(actionNames(resourceSelectItemFuncMap), actionNames(listingFuncMap)...),
+		"VirtualMachineListView": append(actionNames(virtualMachineSelectItemFuncMap), actionNames(listingFuncMap)...),
+		"AKSClusterListView":     append(actionNames(aksClusterSelectItemFuncMap), actionNames(listingFuncMap)...),
+		"HistoryListView":        actionNames(historySelectItemFuncMap),
+		"CommandOutputView":      actionNames(commandOutputFuncMap),
+		"ResourceDetailView":     actionNames(resourceDetailFuncMap),
+		"LogView":                actionNames(logViewFuncMap),
+		"RequestsListView":       actionNames(requestsFuncMap),
+		"BulkRunView":            actionNames(bulkRunFuncMap),
+	}
+}
+
+func actionNames[V any](funcMap map[string]V) []string {
+	names := make([]string, 0, len(funcMap))
+	for name := range funcMap {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,86 @@
+package resourceviews
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brendank310/aztui/pkg/config"
+)
+
+func TestKeyBindingsParsedOncePerConfig(t *testing.T) {
+	if _, err := config.LoadConfig(""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.LoadConfig("") })
+
+	first := keyBindings("SubscriptionListView")
+	if len(first) == 0 {
+		t.Fatal("no default bindings")
+	}
+	if again := keyBindings("SubscriptionListView"); &again[0] != &first[0] {
+		t.Error("bindings parsed again without a new configuration")
+	}
+
+	path := filepath.Join(t.TempDir(), "aztui.yaml")
+	data := "views:\n  - view: SubscriptionListView\n    actions:\n      - action: RefreshView\n        key: Hyper+R\n      - action: ToggleSelection\n        key: x\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := keyBindings("SubscriptionListView")
+	if &reloaded[0] == &first[0] {
+		t.Fatal("bindings not parsed again for the reloaded configuration")
+	}
+	keys := map[string]string{}
+	for _, b := range reloaded {
+		keys[b.action.Action] = b.sequence.String()
+	}
+	if _, ok := keys["RefreshView"]; ok {
+		t.Error("invalid binding kept")
+	}
+	if keys["ToggleSelection"] != "x" {
+		t.Errorf("ToggleSelection bound to %q, want x", keys["ToggleSelection"])
+	}
+}
+
+func TestMatchKeys(t *testing.T) {
+	bindings := []keyBinding{}
+	for action, key := range map[string]string{"Top": "g g", "Go": "g", "Quit": "q"} {
+		sequence, err := config.ParseKeySequence(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bindings = append(bindings, keyBinding{config.Action{Action: action, Key: key}, sequence})
+	}
+
+	tests := []struct {
+		typed  string
+		match  string
+		longer bool
+	}{
+		{"q", "Quit", false},
+		{"g", "Go", true},
+		{"g g", "Top", false},
+		{"x", "", false},
+		{"g x", "", false},
+	}
+
+	for _, tt := range tests {
+		typed, err := config.ParseKeySequence(tt.typed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		match, longer := matchKeys(bindings, typed)
+		got := ""
+		if match != nil {
+			got = match.Action
+		}
+		if got != tt.match || longer != tt.longer {
+			t.Errorf("matchKeys(%q) = %q, %v, want %q, %v", tt.typed, got, longer, tt.match, tt.longer)
+		}
+	}
+}
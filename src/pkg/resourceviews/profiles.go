@@ -0,0 +1,61 @@
+package resourceviews
+
+import (
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const profilesModal = "profiles"
+
+// SwitchProfile lists the configured profiles. Selecting one closes every
+// view and starts over from its subscriptions.
+func (a *AppLayout) SwitchProfile() tview.Primitive {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true)
+	list.SetTitle("Profiles")
+	styleView(list)
+
+	active := config.ActiveProfile().Name
+	for i, profile := range config.Current().Profiles {
+		name := profile.Name
+		label := tview.Escape(name)
+		if name == active {
+			label += " (active)"
+			list.SetCurrentItem(i)
+		}
+		list.AddItem(label, "", 0, func() {
+			a.HideModal(profilesModal)
+			if err := config.SetProfile(name); err != nil {
+				logger.Warn("Not switching profiles", "err", err)
+				return
+			}
+			logger.Info("Switched profile", "profile", name)
+			a.ResetViews()
+		})
+	}
+	if list.GetItemCount() == 0 {
+		list.AddItem("(No profiles configured)", "", 0, func() {
+			a.HideModal(profilesModal)
+		})
+	}
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			a.HideModal(profilesModal)
+			return nil
+		}
+		return event
+	})
+
+	width := len("(No profiles configured)")
+	for _, name := range config.ProfileNames() {
+		if len(name)+len(" (active)") > width {
+			width = len(name) + len(" (active)")
+		}
+	}
+	a.ShowModal(profilesModal, list, width+4, list.GetItemCount()+2)
+
+	return nil
+}
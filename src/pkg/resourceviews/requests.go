@@ -0,0 +1,340 @@
+package resourceviews
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/brendank310/aztui/pkg/azclient"
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const requestsModal = "requests"
+
+var requestsFuncMap = map[string]func(*RequestsListView) tview.Primitive{
+	"ShowRequestDetails": (*RequestsListView).ShowRequestDetails,
+	"ToggleTracing":      (*RequestsListView).ToggleTracing,
+	"ExportHAR":          (*RequestsListView).ExportHAR,
+	"ClearRequests":      (*RequestsListView).ClearRequests,
+	"CloseRequestsView":  (*RequestsListView).CloseRequestsView,
+}
+
+// RequestsListView lists the ARM requests recorded while tracing is on,
+// newest first, as they are made.
+type RequestsListView struct {
+	List   *tview.List
+	Parent *AppLayout
+	Traces []azclient.Trace
+	// Indices into Traces of the listed requests, after filtering
+	listed  []int
+	details tview.Primitive
+	// Set while a refresh for newly recorded requests is queued
+	queued atomic.Bool
+	// Request last selected, kept while it is filtered out, see traceKey
+	selection string
+	// Set while UpdateList fills the list, see listing
+	rendering bool
+}
+
+func NewRequestsListView(layout *AppLayout) *RequestsListView {
+	r := RequestsListView{
+		List:   tview.NewList(),
+		Parent: layout,
+	}
+
+	r.List.SetBorder(true)
+	r.List.ShowSecondaryText(true)
+
+	r.List.SetChangedFunc(func(index int, _, _ string, _ rune) {
+		if !r.rendering && index < len(r.listed) {
+			r.selection = traceKey(r.Traces[r.listed[index]])
+		}
+	})
+	r.List.SetFocusFunc(func() {
+		InitViewKeyBindings(&r)
+		if i := r.Parent.IndexOf(r.List); i != -1 {
+			r.Parent.FocusedViewIndex = i
+		}
+		r.Update()
+		r.UpdateList(r.Parent)
+		r.UpdateActionBar(r.Parent.ActionBar)
+	})
+
+	// Requests are made from any goroutine, see NewLogView
+	azclient.SetTraceFunc(func(azclient.Trace) {
+		if r.queued.CompareAndSwap(false, true) {
+			go queueUpdateDraw(func() {
+				r.queued.Store(false)
+				if r.Parent.IndexOf(r.List) != -1 {
+					r.Update()
+					r.UpdateList(r.Parent)
+				}
+			})
+		}
+	})
+
+	return &r
+}
+
+func (r *RequestsListView) UpdateActionBar(t *tview.TextView) {
+	actionBarText := ""
+	for _, view := range config.Current().Views {
+		if view.Name == r.Name() {
+			for _, action := range view.Actions {
+				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
+			}
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
+			break
+		}
+	}
+
+	t.SetText(actionBarText)
+}
+
+func (r *RequestsListView) Name() string {
+	return "RequestsListView"
+}
+
+func (r *RequestsListView) SetInputCapture(f func(event *tcell.EventKey) *tcell.EventKey) {
+	r.List.SetInputCapture(f)
+}
+
+func (r *RequestsListView) CustomInputHandler() func(event *tcell.EventKey) *tcell.EventKey {
+	return nil
+}
+
+func (r *RequestsListView) CallAction(action string) (tview.Primitive, error) {
+	if actionFunc, ok := requestsFuncMap[action]; ok {
+		return actionFunc(r), nil
+	}
+	return nil, fmt.Errorf("no action for %s", action)
+}
+
+func (r *RequestsListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
+	r.Parent.AppendPrimitiveView(p, takeFocus, width)
+}
+
+func (r *RequestsListView) Update() error {
+	r.Traces = azclient.Traces()
+	return nil
+}
+
+// UpdateList lists the requests whose method, URL, status or request ID
+// contain the search text, keeping the selected request selected and the
+// list scrolled where it was.
+func (r *RequestsListView) UpdateList(layout *AppLayout) error {
+	if t, ok := r.selected(); ok && r.selection == "" {
+		r.selection = traceKey(t)
+	}
+	offset, _ := r.List.GetOffset()
+	r.rendering = true
+	defer func() {
+		r.rendering = false
+	}()
+	current := -1
+
+	title := "Requests"
+	if !azclient.Tracing() {
+		title += " (tracing off)"
+	}
+	r.List.SetTitle(title)
+
+	r.List.Clear()
+	r.listed = []int{}
+
+	// Make filtering case insensitive
+	filter := strings.ToLower(layout.InputField.GetText())
+	for i := len(r.Traces) - 1; i >= 0; i-- {
+		t := r.Traces[i]
+		status := fmt.Sprint(t.Status)
+		if t.Status == 0 {
+			status = "failed"
+		}
+		main := fmt.Sprintf("%v %v %v", t.Method, status, t.URL)
+		if !strings.Contains(strings.ToLower(main+" "+t.RequestID), filter) {
+			continue
+		}
+
+		switch {
+		case t.Status == 0 || t.Status >= 500:
+			status = errorText(status)
+		case t.Status >= 400:
+			status = warningText(status)
+		}
+		secondary := fmt.Sprintf("%v | %v", t.Started.Local().Format("15:04:05"), t.Duration.Round(time.Millisecond))
+		if t.RequestID != "" {
+			secondary += " | " + t.RequestID
+		}
+		if reads, ok := t.RateLimits()["x-ms-ratelimit-remaining-subscription-reads"]; ok {
+			secondary += " | reads left: " + reads
+		}
+		if retryAfter, ok := t.RateLimits()["retry-after"]; ok {
+			secondary += " | " + warningText("retry after "+tview.Escape(retryAfter))
+		}
+
+		r.List.AddItem(fmt.Sprintf("%v %v %v", t.Method, status, tview.Escape(t.URL)), tview.Escape(secondary), 0, nil)
+		if traceKey(t) == r.selection {
+			current = len(r.listed)
+		}
+		r.listed = append(r.listed, i)
+	}
+	if current != -1 {
+		r.List.SetOffset(offset, 0)
+		r.List.SetCurrentItem(current)
+	}
+
+	if len(r.listed) == 0 {
+		text := "(No requests recorded)"
+		if !azclient.Tracing() {
+			text = "(Tracing is off, turn it on to record requests)"
+		}
+		r.List.AddItem(text, "", 0, nil)
+	}
+
+	return nil
+}
+
+// traceKey identifies a recorded request across refreshes.
+func traceKey(t azclient.Trace) string {
+	return t.Started.Format(time.RFC3339Nano) + " " + t.URL
+}
+
+func (r *RequestsListView) selected() (azclient.Trace, bool) {
+	current := r.List.GetCurrentItem()
+	// Traces may have been fetched again since they were listed
+	if current < 0 || current >= len(r.listed) || r.listed[current] >= len(r.Traces) {
+		return azclient.Trace{}, false
+	}
+
+	return r.Traces[r.listed[current]], true
+}
+
+// ShowRequestDetails shows the headers of the selected request and its
+// response.
+func (r *RequestsListView) ShowRequestDetails() tview.Primitive {
+	t, ok := r.selected()
+	if !ok {
+		return nil
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%v %v\n", t.Method, t.URL)
+	fmt.Fprintf(&text, "Started: %v\nDuration: %v\n", t.Started.Local().Format("2006-01-02 15:04:05.000"), t.Duration)
+	if t.Status != 0 {
+		fmt.Fprintf(&text, "Status: %v\n", t.Status)
+	}
+	if t.Err != "" {
+		fmt.Fprintf(&text, "Error: %v\n", t.Err)
+	}
+	writeHeaders(&text, "Request headers", t.RequestHeaders)
+	writeHeaders(&text, "Response headers", t.ResponseHeaders)
+
+	details := tview.NewTextView()
+	details.SetTitle("Request")
+	details.SetBorder(true)
+	details.SetScrollable(true)
+	details.SetText(text.String())
+	r.Parent.ReplaceView(r.details, details, false, 3)
+	r.details = details
+
+	return nil
+}
+
+func writeHeaders(text *strings.Builder, title string, headers map[string][]string) {
+	if len(headers) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(text, "\n%v:\n", title)
+	for _, name := range names {
+		fmt.Fprintf(text, "  %v: %v\n", name, strings.Join(headers[name], ", "))
+	}
+}
+
+// ToggleTracing starts or stops recording requests.
+func (r *RequestsListView) ToggleTracing() tview.Primitive {
+	azclient.SetTracing(!azclient.Tracing())
+	r.UpdateList(r.Parent)
+	return nil
+}
+
+// ExportHAR writes the recorded requests to an HTTP Archive, asking for the
+// file name first. Credentials are redacted when requests are recorded.
+func (r *RequestsListView) ExportHAR() tview.Primitive {
+	form := tview.NewForm()
+	form.SetTitle("Export HAR")
+	form.SetBorder(true)
+	field := tview.NewInputField().
+		SetLabel("File: ").
+		SetText(fmt.Sprintf("aztui-%v.har", time.Now().Format("20060102-150405")))
+	form.AddFormItem(field)
+	form.AddButton("Export", func() {
+		if err := writeHARFile(field.GetText(), azclient.Traces()); err != nil {
+			form.SetTitle("Export HAR - " + errorText(tview.Escape(err.Error())))
+			return
+		}
+		r.Parent.HideModal(requestsModal)
+	})
+	cancel := func() {
+		r.Parent.HideModal(requestsModal)
+	}
+	form.AddButton("Cancel", cancel)
+	form.SetCancelFunc(cancel)
+
+	r.Parent.ShowModal(requestsModal, form, 80, 7)
+	return nil
+}
+
+func writeHARFile(path string, traces []azclient.Trace) error {
+	// Headers can still name subscriptions and resources
+	file, err := os.OpenFile(os.ExpandEnv(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := azclient.WriteHAR(file, traces); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ClearRequests drops the recorded requests.
+func (r *RequestsListView) ClearRequests() tview.Primitive {
+	azclient.ClearTraces()
+	r.Update()
+	r.UpdateList(r.Parent)
+	return nil
+}
+
+func (r *RequestsListView) CloseRequestsView() tview.Primitive {
+	if r.details != nil {
+		r.Parent.Layout.RemoveItem(r.details)
+	}
+	r.Parent.Layout.RemoveItem(r.List)
+	r.Parent.FocusView(0)
+	return nil
+}
+
+// SpawnRequestsView opens the recorded ARM requests, or focuses them when
+// they are already open.
+func (a *AppLayout) SpawnRequestsView() tview.Primitive {
+	if a.requestsView != nil && a.IndexOf(a.requestsView.List) != -1 {
+		a.App.SetFocus(a.requestsView.List)
+		return nil
+	}
+
+	a.requestsView = NewRequestsListView(a)
+	return a.requestsView.List
+}
@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/azclient"
 	"github.com/brendank310/aztui/pkg/config"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 )
 
@@ -17,20 +18,18 @@ var resourceGroupSelectItemFuncMap = map[string]func(*ResourceGroupListView) tvi
 	"SpawnResourceTypeListView":   (*ResourceGroupListView).SpawnResourceTypeListView,
 	"SpawnVirtualMachineListView": (*ResourceGroupListView).SpawnVirtualMachineListView,
 	"SpawnAKSClusterListView":     (*ResourceGroupListView).SpawnAKSClusterListView,
-}
-
-type ResourceGroupInfo struct {
-	ResourceGroupName     string
-	ResourceGroupLocation string
+	"SpawnCommandListView":        (*ResourceGroupListView).SpawnCommandListView,
+	"RefreshView":                 (*ResourceGroupListView).RefreshView,
 }
 
 type ResourceGroupListView struct {
-	List              *tview.List
-	StatusBarText     string
-	ActionBarText     string
-	SubscriptionID    string
-	Parent            *AppLayout
-	ResourceGroupList *[]ResourceGroupInfo
+	List           *tview.List
+	StatusBarText  string
+	ActionBarText  string
+	SubscriptionID string
+	Parent         *AppLayout
+	// The resource groups, see listing
+	listing *listing
 }
 
 func NewResourceGroupListView(appLayout *AppLayout, subscriptionID string) *ResourceGroupListView {
@@ -46,6 +45,10 @@ func NewResourceGroupListView(appLayout *AppLayout, subscriptionID string) *Reso
 	rg.ActionBarText = ""
 	rg.SubscriptionID = subscriptionID
 	rg.Parent = appLayout
+	rg.listing = newListing(rg.Parent, rg.Name(), rg.List, title, listingKey(subscriptionID, "", "resourceGroups"), rg.fetch)
+	rg.listing.render = func() {
+		rg.showMatching(rg.listing.filter)
+	}
 
 	rg.List.SetFocusFunc(func() {
 		InitViewKeyBindings(&rg)
@@ -60,12 +63,12 @@ func NewResourceGroupListView(appLayout *AppLayout, subscriptionID string) *Reso
 
 func (r *ResourceGroupListView) UpdateActionBar(t *tview.TextView) {
 	actionBarText := ""
-	for _, view := range config.GConfig.Views {
+	for _, view := range config.Current().Views {
 		if view.Name == r.Name() {
 			for _, action := range view.Actions {
 				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
 			}
-			actionBarText = actionBarText[:len(actionBarText)-3] // Remove the last " | "
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
 			break
 		}
 	}
@@ -89,7 +92,7 @@ func (r *ResourceGroupListView) CallAction(action string) (tview.Primitive, erro
 	if actionFunc, ok := resourceGroupSelectItemFuncMap[action]; ok {
 		return actionFunc(r), nil
 	}
-	return nil, fmt.Errorf("no action for %s", action)
+	return r.listing.callAction(action)
 }
 
 func (r *ResourceGroupListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
@@ -97,7 +100,11 @@ func (r *ResourceGroupListView) AppendPrimitiveView(p tview.Primitive, takeFocus
 }
 
 func (r *ResourceGroupListView) SpawnResourceTypeListView() tview.Primitive {
-	resourceGroup, _ := r.List.GetItemText(r.List.GetCurrentItem())
+	item, ok := r.listing.selected()
+	if !ok {
+		return nil
+	}
+	resourceGroup := item.Name
 	// Remove previous views if exist starting from the one at index 2
 	r.Parent.RemoveViews(2)
 	rtList := NewResourceTypeListView(r.Parent, r.SubscriptionID, resourceGroup)
@@ -105,7 +112,11 @@ func (r *ResourceGroupListView) SpawnResourceTypeListView() tview.Primitive {
 }
 
 func (r *ResourceGroupListView) SpawnVirtualMachineListView() tview.Primitive {
-	resourceGroup, _ := r.List.GetItemText(r.List.GetCurrentItem())
+	item, ok := r.listing.selected()
+	if !ok {
+		return nil
+	}
+	resourceGroup := item.Name
 	// Remove previous views if exist starting from the one at index 2
 	r.Parent.RemoveViews(2)
 	vmList := NewVirtualMachineListView(r.Parent, r.SubscriptionID, resourceGroup)
@@ -113,54 +124,108 @@ func (r *ResourceGroupListView) SpawnVirtualMachineListView() tview.Primitive {
 }
 
 func (r *ResourceGroupListView) SpawnAKSClusterListView() tview.Primitive {
-	resourceGroup, _ := r.List.GetItemText(r.List.GetCurrentItem())
+	item, ok := r.listing.selected()
+	if !ok {
+		return nil
+	}
+	resourceGroup := item.Name
 	// Remove previous views if exist starting from the one at index 2
 	r.Parent.RemoveViews(2)
 	aksList := NewAKSClusterListView(r.Parent, r.SubscriptionID, resourceGroup)
 	return aksList.List
 }
 
+// Update shows the resource groups, cached ones first, and lists them again
+// in the background.
 func (r *ResourceGroupListView) Update() error {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	r.listing.load()
+	return nil
+}
+
+// RefreshView lists the resource groups again.
+func (r *ResourceGroupListView) RefreshView() tview.Primitive {
+	r.Update()
+	return nil
+}
+
+func (r *ResourceGroupListView) fetch() ([]listItem, error) {
+	cred, err := azclient.Credential()
 	if err != nil {
-		return fmt.Errorf("failed to obtain a credential: %v", err)
+		return nil, fmt.Errorf("failed to obtain a credential: %v", err)
 	}
 
-	r.List.Clear()
-	rgClient, err := armresources.NewResourceGroupsClient(r.SubscriptionID, cred, nil)
+	rgClient, err := armresources.NewResourceGroupsClient(r.SubscriptionID, cred, azclient.ClientOptions())
 	if err != nil {
-		return fmt.Errorf("failed to create resource groups client: %v", err)
+		return nil, fmt.Errorf("failed to create resource groups client: %v", err)
 	}
 
-	r.ResourceGroupList = &[]ResourceGroupInfo{}
-
+	items := []listItem{}
 	rgPager := rgClient.NewListPager(nil)
 	for rgPager.More() {
 		ctx := context.Background()
 		page, err := rgPager.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to get next resource groups page: %v", err)
+			return nil, fmt.Errorf("failed to get next resource groups page: %v", err)
 		}
 		for _, rg := range page.Value {
-			resourceGroup := *rg.Name
-			location := *rg.Location
-			*r.ResourceGroupList = append(*r.ResourceGroupList, ResourceGroupInfo{resourceGroup, location})
-			r.List.AddItem(resourceGroup, location, 0, nil)
+			items = append(items, listItem{Name: *rg.Name, Secondary: *rg.Location, ID: *rg.ID})
 		}
 	}
 
-	return nil
+	return items, nil
 }
 
+// UpdateList shows the resource groups matching the search text.
 func (r *ResourceGroupListView) UpdateList(layout *AppLayout) error {
-	r.List.Clear()
-	// Make filtering case insensitive
-	filter := strings.ToLower(layout.InputField.GetText())
-	for _, ResourceGroupInfo := range *r.ResourceGroupList {
-		lowerCaseResourceGroupName := strings.ToLower(ResourceGroupInfo.ResourceGroupName)
-		if strings.Contains(lowerCaseResourceGroupName, filter) {
-			r.List.AddItem(ResourceGroupInfo.ResourceGroupName, ResourceGroupInfo.ResourceGroupLocation, 0, nil)
+	return r.showMatching(layout.InputField.GetText())
+}
+
+// showMatching shows the resource groups whose name contains filter. The
+// filter is kept for the listings fetched in the background, once the search
+// text has been cleared.
+func (r *ResourceGroupListView) showMatching(filter string) error {
+	items := r.listing.matching(filter)
+	if r.listing.show(items, "(No resource groups in subscription)") {
+		return nil
+	}
+
+	// Start from the profile's resource group
+	if resourceGroup := config.ActiveProfile().ResourceGroup; resourceGroup != "" {
+		for i, item := range r.listing.shown {
+			if strings.EqualFold(item.Name, resourceGroup) {
+				r.List.SetCurrentItem(i)
+				break
+			}
 		}
 	}
 	return nil
 }
+
+func (r *ResourceGroupListView) SpawnCommandListView() tview.Primitive {
+	targets := r.targets("SpawnCommandListView")
+	if len(targets) == 0 {
+		return nil
+	}
+	r.Parent.RemoveViewsAfter(r.List)
+	cmdList := NewBulkCommandListView(r.Parent, targets)
+
+	return cmdList.List
+}
+
+// targets returns the resource groups action acts on, see listing.targets.
+func (r *ResourceGroupListView) targets(action string) []azcli.Target {
+	targets := []azcli.Target{}
+	for _, item := range r.listing.targets(action) {
+		targets = append(targets, r.target(item))
+	}
+	return targets
+}
+
+// target returns the resource group of item.
+func (r *ResourceGroupListView) target(item listItem) azcli.Target {
+	return azcli.Target{
+		SubscriptionID: r.SubscriptionID,
+		Type:           azcli.ResourceGroupType,
+		Name:           item.Name,
+	}
+}
@@ -3,21 +3,23 @@ package resourceviews
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
+	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/azclient"
 	"github.com/brendank310/aztui/pkg/config"
-	"github.com/brendank310/aztui/pkg/logger"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 )
 
 var resourceSelectItemFuncMap = map[string]func(*ResourceListView) tview.Primitive{
 	"SpawnResourceDetailView": (*ResourceListView).SpawnResourceDetailView,
+	"SpawnCommandListView":    (*ResourceListView).SpawnCommandListView,
+	"CopyPortalLink":          (*ResourceListView).CopyPortalLink,
+	"RefreshView":             (*ResourceListView).RefreshView,
 }
 
 type ResourceListView struct {
@@ -29,6 +31,11 @@ type ResourceListView struct {
 	ResourceType   string
 	ReadableName   string
 	Parent         *AppLayout
+	// Listed instead of the resource group of SubscriptionID when set, with
+	// resources of every type when ResourceType is empty
+	Subscriptions []listItem
+	// The resources, see listing
+	listing *listing
 }
 
 func NewResourceListView(layout *AppLayout, subscriptionID, resourceGroup, resourceType string) *ResourceListView {
@@ -49,6 +56,10 @@ func NewResourceListView(layout *AppLayout, subscriptionID, resourceGroup, resou
 	resourceList.ResourceGroup = resourceGroup
 	resourceList.ResourceType = resourceType
 	resourceList.Parent = layout
+	resourceList.listing = newListing(resourceList.Parent, resourceList.Name(), resourceList.List, title, listingKey(subscriptionID, resourceGroup, resourceType), resourceList.fetch)
+	resourceList.listing.render = func() {
+		resourceList.listing.show(resourceList.listing.items, fmt.Sprintf("(No %v in resource group)", resourceType))
+	}
 	layout.FocusedViewIndex = 3
 
 	resourceList.List.SetFocusFunc(func() {
@@ -60,14 +71,31 @@ func NewResourceListView(layout *AppLayout, subscriptionID, resourceGroup, resou
 	return &resourceList
 }
 
+// NewAllResourcesListView lists the resources of every type of every one of
+// subscriptions, with the subscription of each.
+func NewAllResourcesListView(layout *AppLayout, subscriptions []listItem) *ResourceListView {
+	resourceList := NewResourceListView(layout, "", "", "")
+	layout.FocusedViewIndex = 1
+	title := fmt.Sprintf("Resources in %v subscriptions (F%v)", len(subscriptions), layout.FocusedViewIndex+1)
+
+	resourceList.List.Box.SetTitle(title)
+	resourceList.Subscriptions = subscriptions
+	resourceList.listing = newListing(resourceList.Parent, resourceList.Name(), resourceList.List, title, listingKey("", subscriptionScope(subscriptions), "resources"), resourceList.fetch)
+	resourceList.listing.render = func() {
+		resourceList.listing.show(resourceList.listing.items, "(No resources in subscriptions)")
+	}
+
+	return resourceList
+}
+
 func (r *ResourceListView) UpdateActionBar(t *tview.TextView) {
 	actionBarText := ""
-	for _, view := range config.GConfig.Views {
+	for _, view := range config.Current().Views {
 		if view.Name == r.Name() {
 			for _, action := range view.Actions {
 				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
 			}
-			actionBarText = actionBarText[:len(actionBarText)-3] // Remove the last " | "
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
 			break
 		}
 	}
@@ -91,7 +119,7 @@ func (v *ResourceListView) CallAction(action string) (tview.Primitive, error) {
 	if actionFunc, ok := resourceSelectItemFuncMap[action]; ok {
 		return actionFunc(v), nil
 	}
-	return nil, fmt.Errorf("no action for %s", action)
+	return v.listing.callAction(action)
 }
 
 func (v *ResourceListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
@@ -100,95 +128,185 @@ func (v *ResourceListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool
 }
 
 func (v *ResourceListView) SpawnResourceDetailView() tview.Primitive {
-	resourceName, _ := v.List.GetItemText(v.List.GetCurrentItem())
-	// Remove previous views if exist starting from the one at index 4
-	v.Parent.RemoveViews(4)
+	item, ok := v.listing.selected()
+	if !ok {
+		return nil
+	}
+	resourceName := item.Name
+	subscriptionID, resourceGroup, resourceType := v.scopeOf(item)
+	// Remove previous views if exist starting from the one after the list
+	v.Parent.RemoveViews(v.Parent.IndexOf(v.List) + 1)
+
+	v.Parent.FocusedViewIndex = v.Parent.IndexOf(v.List) + 1
+	detail := NewResourceDetailView(v.Parent, resourceName+" Details", func() (interface{}, error) {
+		return getResource(subscriptionID, resourceGroup, resourceType, resourceName)
+	})
+
+	return detail.Pages
+}
 
-	t := tview.NewForm()
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+// getResource returns the resource of type resourceType named resourceName.
+func getResource(subscriptionID, resourceGroup, resourceType, resourceName string) (*armresources.GenericResourceExpanded, error) {
+	cred, err := azclient.Credential()
 	if err != nil {
-		log.Fatalf("Failed to obtain a credential: %v", err)
+		return nil, err
 	}
 
-	ctx := context.Background()
-
-	resourcesClient, err := armresources.NewClient(v.SubscriptionID, cred, nil)
+	resourcesClient, err := armresources.NewClient(subscriptionID, cred, azclient.ClientOptions())
 	if err != nil {
-		log.Fatalf("failed to create resources client: %v", err)
+		return nil, fmt.Errorf("failed to create resources client: %w", err)
 	}
 
-	filter := fmt.Sprintf("resourceType eq '%s' and name eq '%s'", v.ResourceType, resourceName)
+	filter := fmt.Sprintf("resourceType eq '%s' and name eq '%s'", resourceType, resourceName)
 
 	options := &armresources.ClientListByResourceGroupOptions{
 		Filter: &filter,
 		Expand: to.Ptr("$expand=createdTime,provisioningState"),
 	}
 
-	pager := resourcesClient.NewListByResourceGroupPager(v.ResourceGroup, options)
+	pager := resourcesClient.NewListByResourceGroupPager(resourceGroup, options)
 
 	var resource *armresources.GenericResourceExpanded
 	for pager.More() {
-		page, err := pager.NextPage(ctx)
+		page, err := pager.NextPage(context.Background())
 		if err != nil {
-			log.Fatalf("failed to get the next page of resources: %v", err)
+			return nil, fmt.Errorf("failed to get the next page of resources: %w", err)
 		}
 
 		if len(page.Value) == 1 {
 			resource = page.Value[0]
 		} else if len(page.Value) > 1 {
-			log.Fatalf("more than one resource found with the name %s", resourceName)
+			return nil, fmt.Errorf("more than one resource found with the name %s", resourceName)
 		}
 	}
+	if resource == nil {
+		return nil, fmt.Errorf("resource %s not found", resourceName)
+	}
 
-	v.Parent.FocusedViewIndex = 4
-	t.SetTitle(resourceName + " Details")
-	t.AddInputField("Name", resourceName, 0, nil, nil).
-		AddInputField("Resource Type", v.ResourceType, 0, nil, nil).
-		AddInputField("Resource ID", *resource.ID, 0, nil, nil).
-		AddInputField("Location", *resource.Location, 0, nil, nil).
-		AddInputField("Provisioning State", *resource.ProvisioningState, 0, nil, nil)
-	t.SetBorder(true)
-
-	return t
+	return resource, nil
 }
 
+// Update shows the resources, cached ones first, and lists them again in
+// the background.
 func (v *ResourceListView) Update() error {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		return fmt.Errorf("failed to obtain a credential: %v", err)
+	v.listing.load()
+	return nil
+}
+
+// RefreshView lists the resources again.
+func (v *ResourceListView) RefreshView() tview.Primitive {
+	v.Update()
+	return nil
+}
+
+func (v *ResourceListView) fetch() ([]listItem, error) {
+	if len(v.Subscriptions) > 0 {
+		return fanOut(v.Subscriptions, func(subscriptionID string) ([]listItem, error) {
+			return fetchResources(subscriptionID, "", v.ResourceType)
+		})
 	}
+	return fetchResources(v.SubscriptionID, v.ResourceGroup, v.ResourceType)
+}
 
-	v.List.Clear()
+// fetchResources lists the resources of resourceType in resourceGroup, or
+// in the whole subscription when it is empty. Resources of every type are
+// listed with their type when resourceType is empty.
+func fetchResources(subscriptionID, resourceGroup, resourceType string) ([]listItem, error) {
+	cred, err := azclient.Credential()
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain a credential: %v", err)
+	}
 
 	ctx := context.Background()
 
-	resourcesClient, err := armresources.NewClient(v.SubscriptionID, cred, nil)
+	resourcesClient, err := armresources.NewClient(subscriptionID, cred, azclient.ClientOptions())
 	if err != nil {
-		logger.Println("failed to create resources client: ", err)
+		return nil, fmt.Errorf("failed to create resources client: %v", err)
 	}
 
-	filter := fmt.Sprintf("resourceType eq '%s'", v.ResourceType)
+	var filter *string
+	if resourceType != "" {
+		filter = to.Ptr(fmt.Sprintf("resourceType eq '%s'", resourceType))
+	}
+	expand := to.Ptr("$expand=createdTime,provisioningState")
+
+	resources := []*armresources.GenericResourceExpanded{}
+	if resourceGroup == "" {
+		pager := resourcesClient.NewListPager(&armresources.ClientListOptions{Filter: filter, Expand: expand})
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the next page of resources: %v", err)
+			}
+			resources = append(resources, page.Value...)
+		}
+	} else {
+		pager := resourcesClient.NewListByResourceGroupPager(resourceGroup, &armresources.ClientListByResourceGroupOptions{Filter: filter, Expand: expand})
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the next page of resources: %v", err)
+			}
+			resources = append(resources, page.Value...)
+		}
+	}
 
-	options := &armresources.ClientListByResourceGroupOptions{
-		Filter: &filter,
-		Expand: to.Ptr("$expand=createdTime,provisioningState"),
+	items := []listItem{}
+	for _, resource := range resources {
+		secondary := *resource.Location
+		if resourceType == "" && resource.Type != nil {
+			secondary = *resource.Type + " | " + secondary
+		}
+		items = append(items, listItem{Name: *resource.Name, Secondary: secondary, ID: *resource.ID})
 	}
 
-	pager := resourcesClient.NewListByResourceGroupPager(v.ResourceGroup, options)
+	return items, nil
+}
 
-	if !pager.More() {
-		v.List.AddItem(fmt.Sprintf("(No %v in resource group)", v.ResourceType), "", 0, nil)
+// scopeOf returns the subscription, resource group and type of the resource
+// of item.
+func (v *ResourceListView) scopeOf(item listItem) (string, string, string) {
+	if len(v.Subscriptions) == 0 {
+		return v.SubscriptionID, v.ResourceGroup, v.ResourceType
 	}
+	return item.scope()
+}
 
-	for pager.More() {
-		page, err := pager.NextPage(ctx)
-		if err != nil {
-			logger.Println("failed to get the next page of", v.ResourceType, ":", err)
-		}
+func (v *ResourceListView) SpawnCommandListView() tview.Primitive {
+	targets := v.targets("SpawnCommandListView")
+	if len(targets) == 0 {
+		return nil
+	}
+	v.Parent.RemoveViewsAfter(v.List)
+	cmdList := NewBulkCommandListView(v.Parent, targets)
 
-		for _, resource := range page.Value {
-			v.List.AddItem(*resource.Name, *resource.Location, 0, nil)
-		}
+	return cmdList.List
+}
+
+// targets returns the resources action acts on, see listing.targets.
+func (v *ResourceListView) targets(action string) []azcli.Target {
+	targets := []azcli.Target{}
+	for _, item := range v.listing.targets(action) {
+		targets = append(targets, v.target(item))
+	}
+	return targets
+}
+
+// target returns the resource of item.
+func (v *ResourceListView) target(item listItem) azcli.Target {
+	subscriptionID, resourceGroup, resourceType := v.scopeOf(item)
+	return azcli.Target{
+		SubscriptionID: subscriptionID,
+		ResourceGroup:  resourceGroup,
+		Type:           resourceType,
+		Name:           item.Name,
+	}
+}
+
+// CopyPortalLink copies the link to the selected resource in the portal.
+func (v *ResourceListView) CopyPortalLink() tview.Primitive {
+	if targets := v.targets("CopyPortalLink"); len(targets) > 0 {
+		v.Parent.CopyPortalLink(targets...)
 	}
 
 	return nil
@@ -3,11 +3,11 @@ package resourceviews
 import (
 	"context"
 	"fmt"
-	"log"
+	"sort"
 	"strings"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/brendank310/aztui/pkg/azclient"
 	"github.com/brendank310/aztui/pkg/config"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -15,21 +15,19 @@ import (
 
 var resourceTypeSelectItemFuncMap = map[string]func(*ResourceTypeListView) tview.Primitive{
 	"SpawnResourceListView": (*ResourceTypeListView).SpawnResourceListView,
-}
-
-type ResourceTypeInfo struct {
-	Name         string
-	ReadableName string
+	"RefreshView":           (*ResourceTypeListView).RefreshView,
 }
 
 type ResourceTypeListView struct {
-	List             *tview.List
-	StatusBarText    string
-	ActionBarText    string
-	SubscriptionID   string
-	ResourceGroup    string
-	Parent           *AppLayout
-	ResourceTypeList map[string]ResourceTypeInfo
+	List           *tview.List
+	StatusBarText  string
+	ActionBarText  string
+	SubscriptionID string
+	ResourceGroup  string
+	Parent         *AppLayout
+	// The resource types, named without "Microsoft." and with the type as
+	// ID, see listing
+	listing *listing
 }
 
 func NewResourceTypeListView(layout *AppLayout, subscriptionID, resourceGroup string) *ResourceTypeListView {
@@ -46,6 +44,7 @@ func NewResourceTypeListView(layout *AppLayout, subscriptionID, resourceGroup st
 	rt.SubscriptionID = subscriptionID
 	rt.ResourceGroup = resourceGroup
 	rt.Parent = layout
+	rt.listing = newListing(rt.Parent, rt.Name(), rt.List, title, listingKey(subscriptionID, resourceGroup, "resourceTypes"), rt.fetch)
 	layout.FocusedViewIndex = 2
 
 	rt.List.SetFocusFunc(func() {
@@ -59,12 +58,12 @@ func NewResourceTypeListView(layout *AppLayout, subscriptionID, resourceGroup st
 
 func (r *ResourceTypeListView) UpdateActionBar(t *tview.TextView) {
 	actionBarText := ""
-	for _, view := range config.GConfig.Views {
+	for _, view := range config.Current().Views {
 		if view.Name == r.Name() {
 			for _, action := range view.Actions {
 				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
 			}
-			actionBarText = actionBarText[:len(actionBarText)-3] // Remove the last " | "
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
 			break
 		}
 	}
@@ -88,7 +87,7 @@ func (r *ResourceTypeListView) CallAction(action string) (tview.Primitive, error
 	if actionFunc, ok := resourceTypeSelectItemFuncMap[action]; ok {
 		return actionFunc(r), nil
 	}
-	return nil, fmt.Errorf("no action for %s", action)
+	return r.listing.callAction(action)
 }
 
 func (r *ResourceTypeListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
@@ -96,8 +95,11 @@ func (r *ResourceTypeListView) AppendPrimitiveView(p tview.Primitive, takeFocus
 }
 
 func (r *ResourceTypeListView) SpawnResourceListView() tview.Primitive {
-	readableName, _ := r.List.GetItemText(r.List.GetCurrentItem())
-	resourceType := r.ResourceTypeList[readableName].Name
+	item, ok := r.listing.selected()
+	if !ok {
+		return nil
+	}
+	resourceType := item.ID
 	// Remove previous views if exist strating from the one at index 3
 	r.Parent.RemoveViews(3)
 
@@ -118,48 +120,59 @@ func (r *ResourceTypeListView) SpawnResourceListView() tview.Primitive {
 	return resourceList.List
 }
 
+// Update shows the resource types, cached ones first, and lists them again
+// in the background.
 func (r *ResourceTypeListView) Update() error {
-	// Create a credential using the default Azure credential chain
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	r.listing.load()
+	return nil
+}
+
+// RefreshView lists the resource types again.
+func (r *ResourceTypeListView) RefreshView() tview.Primitive {
+	r.Update()
+	return nil
+}
+
+func (r *ResourceTypeListView) fetch() ([]listItem, error) {
+	cred, err := azclient.Credential()
 	if err != nil {
-		log.Fatalf("failed to obtain a credential: %v", err)
+		return nil, fmt.Errorf("failed to obtain a credential: %v", err)
 	}
 
 	// Create a context
 	ctx := context.Background()
 
 	// Create a client to interact with the resource management APIs
-	resourcesClient, err := armresources.NewClient(r.SubscriptionID, cred, nil)
+	resourcesClient, err := armresources.NewClient(r.SubscriptionID, cred, azclient.ClientOptions())
 	if err != nil {
-		log.Fatalf("failed to create resources client: %v", err)
+		return nil, fmt.Errorf("failed to create resources client: %v", err)
 	}
 
 	// Create a pager to list resources in the specified resource group
 	pager := resourcesClient.NewListByResourceGroupPager(r.ResourceGroup, nil)
 
-	r.List.Clear()
-	// Create a map to store unique resource types
-	r.ResourceTypeList = make(map[string]ResourceTypeInfo, 0)
-	// Iterate through the pages and collect resource types
+	// Collect the unique resource types
+	types := map[string]bool{}
 	for pager.More() {
 		page, err := pager.NextPage(ctx)
 		if err != nil {
-			log.Fatalf("failed to get the next page of results: %v", err)
+			return nil, fmt.Errorf("failed to get the next page of resources: %v", err)
 		}
 
 		for _, resource := range page.Value {
 			if resource.Type != nil {
-				resourceType := *resource.Type
-				name := resourceType
-				readableName := strings.TrimPrefix(resourceType, "Microsoft.")
-				(r.ResourceTypeList)[readableName] = ResourceTypeInfo{name, readableName}
+				types[*resource.Type] = true
 			}
 		}
 	}
 
-	for _, resourceTypeInfo := range r.ResourceTypeList {
-		r.List.AddItem(resourceTypeInfo.ReadableName, "", 0, nil)
+	items := []listItem{}
+	for resourceType := range types {
+		items = append(items, listItem{Name: strings.TrimPrefix(resourceType, "Microsoft."), ID: resourceType})
 	}
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
 
-	return nil
+	return items, nil
 }
@@ -5,21 +5,20 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/brendank310/aztui/pkg/azclient"
 	"github.com/brendank310/aztui/pkg/config"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armsubscriptions"
 )
 
 var subscriptionSelectItemFuncMap = map[string]func(*SubscriptionListView) tview.Primitive{
-	"SpawnResourceGroupListView": (*SubscriptionListView).SpawnResourceGroupListView,
-}
-
-type SubscriptionInfo struct {
-	SubscriptionName string
-	SubscriptionID   string
+	"SpawnResourceGroupListView":  (*SubscriptionListView).SpawnResourceGroupListView,
+	"SpawnAllVirtualMachinesView": (*SubscriptionListView).SpawnAllVirtualMachinesView,
+	"SpawnAllAKSClustersView":     (*SubscriptionListView).SpawnAllAKSClustersView,
+	"SpawnAllResourcesView":       (*SubscriptionListView).SpawnAllResourcesView,
+	"RefreshView":                 (*SubscriptionListView).RefreshView,
 }
 
 type SubscriptionListView struct {
@@ -27,8 +26,9 @@ type SubscriptionListView struct {
 	StatusBarText         string
 	ActionBarText         string
 	Parent                *AppLayout
-	SubscriptionList      *[]SubscriptionInfo
 	ResourceGroupListView *ResourceGroupListView
+	// The subscriptions, see listing
+	listing *listing
 }
 
 func NewSubscriptionListView(appLayout *AppLayout) *SubscriptionListView {
@@ -42,6 +42,10 @@ func NewSubscriptionListView(appLayout *AppLayout) *SubscriptionListView {
 	s.List.Box.SetTitle(title)
 	s.ActionBarText = ""
 	s.Parent = appLayout
+	s.listing = newListing(s.Parent, s.Name(), s.List, title, listingKey("", "", "subscriptions"), s.fetch)
+	s.listing.render = func() {
+		s.showMatching(s.listing.filter)
+	}
 
 	s.List.SetFocusFunc(func() {
 		InitViewKeyBindings(&s)
@@ -57,12 +61,12 @@ func NewSubscriptionListView(appLayout *AppLayout) *SubscriptionListView {
 
 func (s *SubscriptionListView) UpdateActionBar(t *tview.TextView) {
 	actionBarText := ""
-	for _, view := range config.GConfig.Views {
+	for _, view := range config.Current().Views {
 		if view.Name == s.Name() {
 			for _, action := range view.Actions {
 				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
 			}
-			actionBarText = actionBarText[:len(actionBarText)-3] // Remove the last " | "
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
 			break
 		}
 	}
@@ -86,7 +90,7 @@ func (s *SubscriptionListView) CallAction(action string) (tview.Primitive, error
 	if actionFunc, ok := subscriptionSelectItemFuncMap[action]; ok {
 		return actionFunc(s), nil
 	}
-	return nil, fmt.Errorf("no action for %s", action)
+	return s.listing.callAction(action)
 }
 
 func (s *SubscriptionListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
@@ -94,7 +98,11 @@ func (s *SubscriptionListView) AppendPrimitiveView(p tview.Primitive, takeFocus
 }
 
 func (s *SubscriptionListView) SpawnResourceGroupListView() tview.Primitive {
-	_, subscriptionID := s.List.GetItemText(s.List.GetCurrentItem())
+	item, ok := s.listing.selected()
+	if !ok {
+		return nil
+	}
+	subscriptionID := item.ID
 	s.Parent.RemoveViews(1)
 	rgList := NewResourceGroupListView(s.Parent, subscriptionID)
 	s.ResourceGroupListView = rgList
@@ -102,49 +110,112 @@ func (s *SubscriptionListView) SpawnResourceGroupListView() tview.Primitive {
 	return rgList.List
 }
 
+// subscriptionsFor returns the subscriptions the views across subscriptions
+// spawned by action list: the selected ones when there are any and the
+// action is configured with multiple: true, otherwise every one listed.
+func (s *SubscriptionListView) subscriptionsFor(action string) []listItem {
+	if marked := s.listing.marked(); len(marked) > 0 && multipleTargets(s.Name(), action) {
+		return marked
+	}
+
+	subscriptions := []listItem{}
+	for _, item := range s.listing.shown {
+		if item.Err == "" && s.listing.changes[item.identity()] != removed {
+			subscriptions = append(subscriptions, item)
+		}
+	}
+	return subscriptions
+}
+
+// SpawnAllVirtualMachinesView lists the virtual machines of the listed or
+// selected subscriptions.
+func (s *SubscriptionListView) SpawnAllVirtualMachinesView() tview.Primitive {
+	subscriptions := s.subscriptionsFor("SpawnAllVirtualMachinesView")
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	s.Parent.RemoveViews(1)
+	return NewAllVirtualMachinesListView(s.Parent, subscriptions).List
+}
+
+// SpawnAllAKSClustersView lists the AKS clusters of the listed or selected
+// subscriptions.
+func (s *SubscriptionListView) SpawnAllAKSClustersView() tview.Primitive {
+	subscriptions := s.subscriptionsFor("SpawnAllAKSClustersView")
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	s.Parent.RemoveViews(1)
+	return NewAllAKSClustersListView(s.Parent, subscriptions).List
+}
+
+// SpawnAllResourcesView lists the resources of the listed or selected
+// subscriptions.
+func (s *SubscriptionListView) SpawnAllResourcesView() tview.Primitive {
+	subscriptions := s.subscriptionsFor("SpawnAllResourcesView")
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	s.Parent.RemoveViews(1)
+	return NewAllResourcesListView(s.Parent, subscriptions).List
+}
+
+// Update shows the subscriptions, cached ones first, and lists them again in
+// the background.
 func (s *SubscriptionListView) Update() error {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	s.listing.load()
+	return nil
+}
+
+// RefreshView lists the subscriptions again.
+func (s *SubscriptionListView) RefreshView() tview.Primitive {
+	s.Update()
+	return nil
+}
+
+func (s *SubscriptionListView) fetch() ([]listItem, error) {
+	cred, err := azclient.Credential()
 	if err != nil {
-		return fmt.Errorf("failed to obtain a credential: %v", err)
+		return nil, fmt.Errorf("failed to obtain a credential: %v", err)
 	}
 
-	subClient, err := armsubscriptions.NewClient(cred, nil)
+	subClient, err := armsubscriptions.NewClient(cred, azclient.ClientOptions())
 	if err != nil {
-		return fmt.Errorf("failed to create subscriptions client: %v", err)
+		return nil, fmt.Errorf("failed to create subscriptions client: %v", err)
 	}
 
-	// Initialize the subscription list
-	s.SubscriptionList = &[]SubscriptionInfo{}
-	s.List.Clear()
-
-	// List subscriptions
+	// List subscriptions, limited to those of the profile
+	items := []listItem{}
+	profile := config.ActiveProfile()
 	subPager := subClient.NewListPager(nil)
 	ctx := context.Background()
 	for subPager.More() {
 		page, err := subPager.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to get next subscriptions page: %v", err)
+			return nil, fmt.Errorf("failed to get next subscriptions page: %v", err)
 		}
 		for _, subscription := range page.Value {
 			subscriptionID := *subscription.SubscriptionID
 			subscriptionName := *subscription.DisplayName
-			s.List.AddItem(subscriptionName, subscriptionID, 0, nil)
-			*s.SubscriptionList = append(*s.SubscriptionList, SubscriptionInfo{subscriptionName, subscriptionID})
+			if !profile.MatchesSubscription(subscriptionName, subscriptionID) {
+				continue
+			}
+			items = append(items, listItem{Name: subscriptionName, Secondary: subscriptionID, ID: subscriptionID})
 		}
 	}
 
-	return nil
+	return items, nil
 }
 
+// UpdateList shows the subscriptions matching the search text.
 func (s *SubscriptionListView) UpdateList(layout *AppLayout) error {
-	s.List.Clear()
-	// Make filtering case insensitive
-	filter := strings.ToLower(layout.InputField.GetText())
-	for _, SubscriptionInfo := range *s.SubscriptionList {
-		lowerCaseSubscriptionName := strings.ToLower(SubscriptionInfo.SubscriptionName)
-		if strings.Contains(lowerCaseSubscriptionName, filter) {
-			s.List.AddItem(SubscriptionInfo.SubscriptionName, SubscriptionInfo.SubscriptionID, 0, nil)
-		}
-	}
+	return s.showMatching(layout.InputField.GetText())
+}
+
+// showMatching shows the subscriptions whose name contains filter, which is
+// kept for the listings fetched in the background.
+func (s *SubscriptionListView) showMatching(filter string) error {
+	items := s.listing.matching(filter)
+	s.listing.show(items, "(No subscriptions)")
 	return nil
 }
@@ -0,0 +1,150 @@
+package resourceviews
+
+import (
+	"fmt"
+
+	"github.com/brendank310/aztui/pkg/config"
+	"github.com/brendank310/aztui/pkg/logger"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// The resolved theme of the configuration, see applyTheme
+var theme config.Theme
+
+// applyTheme resolves the configured theme and makes it the one primitives
+// are created with. It has to run before the primitives are created, views
+// that already exist are restyled with styleView.
+func applyTheme() {
+	resolved, err := config.Current().Theme.Resolve()
+	if err != nil {
+		// Caught by validation, unless the configuration was never validated
+		logger.Warn("Using the default theme", "err", err)
+		resolved, _ = config.Theme{}.Resolve()
+	}
+	theme = resolved
+
+	tview.Styles = tview.Theme{
+		PrimitiveBackgroundColor:    themeColor(theme.Background),
+		ContrastBackgroundColor:     themeColor(theme.Input),
+		MoreContrastBackgroundColor: themeColor(theme.Input),
+		BorderColor:                 themeColor(theme.Border),
+		TitleColor:                  themeColor(theme.Title),
+		GraphicsColor:               themeColor(theme.Border),
+		PrimaryTextColor:            themeColor(theme.Text),
+		SecondaryTextColor:          themeColor(theme.Label),
+		TertiaryTextColor:           themeColor(theme.SecondaryText),
+		InverseTextColor:            themeColor(theme.Input),
+		ContrastSecondaryTextColor:  themeColor(theme.SecondaryText),
+	}
+}
+
+// Theme colors are validated with the configuration, anything invalid left
+// is drawn in the terminal's own color.
+func themeColor(name string) tcell.Color {
+	color, _ := config.ParseColor(name)
+	return color
+}
+
+// selectionStyle is the style of selected list items and table cells. A
+// theme without a selection color, such as no-color, reverses the item.
+func selectionStyle() tcell.Style {
+	background, text := themeColor(theme.Selection), themeColor(theme.SelectionText)
+	if background == tcell.ColorDefault {
+		return tcell.StyleDefault.Reverse(true)
+	}
+	return tcell.StyleDefault.Background(background).Foreground(text)
+}
+
+// colorize wraps text in a color tag for views with dynamic colors. Text in
+// the terminal's own color is left untagged, so no-color shows plain text.
+func colorize(color, text string) string {
+	if themeColor(color) == tcell.ColorDefault {
+		return text
+	}
+	return fmt.Sprintf("[%v]%v[-]", color, text)
+}
+
+func errorText(text string) string {
+	return colorize(theme.Error, text)
+}
+
+func warningText(text string) string {
+	return colorize(theme.Warning, text)
+}
+
+func successText(text string) string {
+	return colorize(theme.Success, text)
+}
+
+// powerStateText colors a virtual machine power state such as "running".
+func powerStateText(state string) string {
+	return colorize(theme.PowerStateColor(state), state)
+}
+
+// styleView restyles p with the current theme. Primitives pick the theme up
+// from tview.Styles when they are created, apart from the selection colors,
+// so this only matters for selections and after the theme changed.
+func styleView(p tview.Primitive) {
+	switch v := p.(type) {
+	case *tview.List:
+		styleBox(v.Box)
+		v.SetMainTextColor(tview.Styles.PrimaryTextColor).
+			SetSecondaryTextColor(tview.Styles.TertiaryTextColor).
+			SetShortcutColor(tview.Styles.SecondaryTextColor).
+			SetSelectedStyle(selectionStyle())
+	case *tview.Table:
+		styleBox(v.Box)
+		v.SetSelectedStyle(selectionStyle())
+	case *tview.TreeView:
+		styleBox(v.Box)
+		v.SetGraphicsColor(tview.Styles.GraphicsColor)
+	case *tview.TextView:
+		styleBox(v.Box)
+		v.SetTextColor(tview.Styles.PrimaryTextColor)
+	case *tview.InputField:
+		styleBox(v.Box)
+		v.SetLabelColor(tview.Styles.SecondaryTextColor).
+			SetFieldBackgroundColor(tview.Styles.ContrastBackgroundColor).
+			SetFieldTextColor(tview.Styles.PrimaryTextColor)
+	case *tview.Form:
+		styleBox(v.Box)
+		v.SetLabelColor(tview.Styles.SecondaryTextColor).
+			SetFieldBackgroundColor(tview.Styles.ContrastBackgroundColor).
+			SetFieldTextColor(tview.Styles.PrimaryTextColor).
+			SetButtonBackgroundColor(tview.Styles.ContrastBackgroundColor).
+			SetButtonTextColor(tview.Styles.PrimaryTextColor)
+	case *tview.Flex:
+		styleBox(v.Box)
+		for i := 0; i < v.GetItemCount(); i++ {
+			styleView(v.GetItem(i))
+		}
+	case *tview.Grid:
+		styleBox(v.Box)
+		v.SetBordersColor(tview.Styles.GraphicsColor)
+	case *tview.Pages:
+		styleBox(v.Box)
+	}
+}
+
+func styleBox(b *tview.Box) {
+	b.SetBackgroundColor(tview.Styles.PrimitiveBackgroundColor).
+		SetBorderColor(tview.Styles.BorderColor).
+		SetTitleColor(tview.Styles.TitleColor)
+}
+
+// restyle applies the theme to the layout and the views in it. Views made of
+// several pages, such as JSON views, keep parts of a previous theme until
+// they are opened again.
+func (a *AppLayout) restyle() {
+	styleView(a.Grid)
+	for _, p := range []tview.Primitive{a.titleBar, a.InputField, a.body, a.statusBar, a.ActionBar} {
+		styleView(p)
+	}
+	// Styled while hidden too, it keeps its colors until it is shown
+	styleView(a.logView.TextView)
+	a.logView.Update()
+	a.statusBar.SetTextColor(themeColor(theme.Status))
+	a.ActionBar.SetTextColor(themeColor(theme.Status))
+	a.updateTitleBar()
+}
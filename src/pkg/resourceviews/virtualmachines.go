@@ -3,16 +3,18 @@ package resourceviews
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
+	"sync"
 
 	"github.com/brendank310/aztui/pkg/azcli"
+	"github.com/brendank310/aztui/pkg/azclient"
 	"github.com/brendank310/aztui/pkg/config"
 	"github.com/brendank310/aztui/pkg/consoles"
+	"github.com/brendank310/aztui/pkg/logger"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 )
 
@@ -20,6 +22,9 @@ var virtualMachineSelectItemFuncMap = map[string]func(*VirtualMachineListView) t
 	"SpawnVirtualMachineDetailView":        (*VirtualMachineListView).SpawnVirtualMachineDetailView,
 	"SpawnVirtualMachineSerialConsoleView": (*VirtualMachineListView).SpawnVirtualMachineSerialConsoleView,
 	"SpawnVirtualMachineCommandListView":   (*VirtualMachineListView).SpawnVirtualMachineCommandListView,
+	"SpawnCommandListView":                 (*VirtualMachineListView).SpawnCommandListView,
+	"CopyPortalLink":                       (*VirtualMachineListView).CopyPortalLink,
+	"RefreshView":                          (*VirtualMachineListView).RefreshView,
 }
 
 type VirtualMachineListView struct {
@@ -29,6 +34,10 @@ type VirtualMachineListView struct {
 	SubscriptionID string
 	ResourceGroup  string
 	Parent         *AppLayout
+	// Listed instead of the resource group of SubscriptionID when set
+	Subscriptions []listItem
+	// The virtual machines with their power states, see listing
+	listing *listing
 }
 
 func NewVirtualMachineListView(appLayout *AppLayout, subscriptionID string, resourceGroup string) *VirtualMachineListView {
@@ -47,6 +56,10 @@ func NewVirtualMachineListView(appLayout *AppLayout, subscriptionID string, reso
 	vm.SubscriptionID = subscriptionID
 	vm.ResourceGroup = resourceGroup
 	vm.Parent = appLayout
+	vm.listing = newListing(vm.Parent, vm.Name(), vm.List, title, listingKey(subscriptionID, resourceGroup, "Microsoft.Compute/virtualMachines"), vm.fetch)
+	vm.listing.render = func() {
+		vm.listing.show(vm.listing.items, "(No VMs in resource group)")
+	}
 
 	vm.List.SetFocusFunc(func() {
 		InitViewKeyBindings(&vm)
@@ -57,14 +70,31 @@ func NewVirtualMachineListView(appLayout *AppLayout, subscriptionID string, reso
 	return &vm
 }
 
+// NewAllVirtualMachinesListView lists the virtual machines of every one of
+// subscriptions, with the subscription of each.
+func NewAllVirtualMachinesListView(appLayout *AppLayout, subscriptions []listItem) *VirtualMachineListView {
+	vm := NewVirtualMachineListView(appLayout, "", "")
+	appLayout.FocusedViewIndex = 1
+	title := fmt.Sprintf("Virtual Machines in %v subscriptions (F%v)", len(subscriptions), appLayout.FocusedViewIndex+1)
+
+	vm.List.Box.SetTitle(title)
+	vm.Subscriptions = subscriptions
+	vm.listing = newListing(vm.Parent, vm.Name(), vm.List, title, listingKey("", subscriptionScope(subscriptions), "Microsoft.Compute/virtualMachines"), vm.fetch)
+	vm.listing.render = func() {
+		vm.listing.show(vm.listing.items, "(No VMs in subscriptions)")
+	}
+
+	return vm
+}
+
 func (v *VirtualMachineListView) UpdateActionBar(t *tview.TextView) {
 	actionBarText := ""
-	for _, view := range config.GConfig.Views {
+	for _, view := range config.Current().Views {
 		if view.Name == v.Name() {
 			for _, action := range view.Actions {
 				actionBarText += fmt.Sprintf("%v(%v) | ", action.Description, action.Key)
 			}
-			actionBarText = actionBarText[:len(actionBarText)-3] // Remove the last " | "
+			actionBarText = strings.TrimSuffix(actionBarText, " | ")
 			break
 		}
 	}
@@ -88,7 +118,7 @@ func (v *VirtualMachineListView) CallAction(action string) (tview.Primitive, err
 	if actionFunc, ok := virtualMachineSelectItemFuncMap[action]; ok {
 		return actionFunc(v), nil
 	}
-	return nil, fmt.Errorf("no action for %s", action)
+	return v.listing.callAction(action)
 }
 
 func (v *VirtualMachineListView) AppendPrimitiveView(p tview.Primitive, takeFocus bool, width int) {
@@ -96,41 +126,43 @@ func (v *VirtualMachineListView) AppendPrimitiveView(p tview.Primitive, takeFocu
 }
 
 func (v *VirtualMachineListView) SpawnVirtualMachineDetailView() tview.Primitive {
-	vmName, _ := v.List.GetItemText(v.List.GetCurrentItem())
-	v.Parent.RemoveViews(4)
-	t := tview.NewForm()
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		log.Fatalf("Failed to obtain a credential: %v", err)
-	}
-
-	// Create a context
-	ctx := context.Background()
-
-	// Create a Compute Virtual Machines client
-	vmClient, err := armcompute.NewVirtualMachinesClient(v.SubscriptionID, cred, nil)
-	if err != nil {
-		log.Fatalf("Failed to create VM client: %v", err)
+	item, ok := v.listing.selected()
+	if !ok {
+		return nil
 	}
+	vmName := item.Name
+	subscriptionID, resourceGroup := v.scopeOf(item)
+	v.Parent.RemoveViews(v.Parent.IndexOf(v.List) + 2)
+	detail := NewResourceDetailView(v.Parent, vmName+" Details", func() (interface{}, error) {
+		cred, err := azclient.Credential()
+		if err != nil {
+			return nil, err
+		}
 
-	vm, err := vmClient.Get(ctx, v.ResourceGroup, vmName, nil)
-	if err != nil {
-		log.Fatalf("Failed to get VM: %v", err)
-	}
+		// Create a Compute Virtual Machines client
+		vmClient, err := armcompute.NewVirtualMachinesClient(subscriptionID, cred, azclient.ClientOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create VM client: %w", err)
+		}
 
-	t.SetTitle(vmName + " Details")
-	t.AddInputField("VM Name", *vm.Name, 0, nil, nil).
-		AddInputField("Resource ID", *vm.ID, 0, nil, nil).
-		AddInputField("Location", *vm.Location, 0, nil, nil).
-		AddInputField("OS", string(*vm.Properties.StorageProfile.OSDisk.OSType), 0, nil, nil)
-	t.SetBorder(true)
+		vm, err := vmClient.Get(context.Background(), resourceGroup, vmName, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get VM: %w", err)
+		}
+		return vm.VirtualMachine, nil
+	})
 
-	return t
+	return detail.Pages
 }
 
 func (v *VirtualMachineListView) SpawnVirtualMachineSerialConsoleView() tview.Primitive {
-	vmName, _ := v.List.GetItemText(v.List.GetCurrentItem())
-	t := consoles.StartSerialConsoleMonitor(v.SubscriptionID, v.ResourceGroup, vmName)
+	item, ok := v.listing.selected()
+	if !ok {
+		return nil
+	}
+	vmName := item.Name
+	subscriptionID, resourceGroup := v.scopeOf(item)
+	t := consoles.StartSerialConsoleMonitor(subscriptionID, resourceGroup, vmName)
 	t.SetChangedFunc(func() {
 		v.Parent.App.Draw()
 	})
@@ -139,100 +171,204 @@ func (v *VirtualMachineListView) SpawnVirtualMachineSerialConsoleView() tview.Pr
 }
 
 func (v *VirtualMachineListView) SpawnVirtualMachineCommandListView() tview.Primitive {
-	vmName, _ := v.List.GetItemText(v.List.GetCurrentItem())
-	v.Parent.RemoveViews(4)
-	cmdMap, err := azcli.GetResourceCommands("vm")
+	return v.spawnCommandListView("SpawnVirtualMachineCommandListView")
+}
+
+// Update shows the virtual machines, cached ones first, and lists them
+// again in the background.
+func (v *VirtualMachineListView) Update() error {
+	v.listing.load()
+	return nil
+}
+
+// RefreshView lists the virtual machines again.
+func (v *VirtualMachineListView) RefreshView() tview.Primitive {
+	v.Update()
+	return nil
+}
+
+func (v *VirtualMachineListView) fetch() ([]listItem, error) {
+	if len(v.Subscriptions) > 0 {
+		return fanOut(v.Subscriptions, func(subscriptionID string) ([]listItem, error) {
+			return fetchVirtualMachines(subscriptionID, "")
+		})
+	}
+	return fetchVirtualMachines(v.SubscriptionID, v.ResourceGroup)
+}
+
+// fetchVirtualMachines lists the virtual machines of resourceGroup, or of
+// the whole subscription when it is empty.
+func fetchVirtualMachines(subscriptionID, resourceGroup string) ([]listItem, error) {
+	cred, err := azclient.Credential()
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain a credential: %v", err)
+	}
+
+	vmClient, err := armcompute.NewVirtualMachinesClient(subscriptionID, cred, azclient.ClientOptions())
 	if err != nil {
-		panic(err)
-	}
-
-	cmdList := tview.NewList()
-	cmdList.SetTitle("VM Commands")
-	cmdList.SetBorder(true)
-	for k0, v0 := range cmdMap {
-		cmdList.AddItem(k0, v0, 0, func() {
-			cmdStr, _ := cmdList.GetItemText(cmdList.GetCurrentItem())
-			args := []string{"vm", cmdStr, "-g", v.ResourceGroup, "-n", vmName}
-			out, err := azcli.RunAzCommand(args, func(a []string, err error) error {
-				if strings.HasPrefix(err.Error(), "ERROR: InvalidArgumentValue:") {
-					newArgs := a
-					cmdForm := tview.NewForm()
-
-					missingArg := strings.Split(err.Error(), "field:")[1]
-					cmdForm.AddInputField("Missing argument: "+missingArg, "", 0, nil, func(text string) {
-						newArgs = append(newArgs, missingArg)
-						newArgs = append(newArgs, text)
-						_, _ = azcli.RunAzCommand(newArgs, nil)
-					})
-				}
-
-				if strings.HasSuffix(err.Error(), "are required\n") {
-					newArgs := a
-					cmdForm := tview.NewForm()
-					extractRequiredArgs := strings.Split(err.Error(), ":")[1]
-					missingArgs := strings.Split(strings.TrimSuffix(strings.Replace(strings.Replace(extractRequiredArgs, "(", "", 1), ")", "", 1), " are required\n"), "|")
-
-					for _, arg := range missingArgs {
-						cmdForm.AddInputField("Missing argument: "+arg, "", 0, nil, func(text string) {
-							newArgs = append(newArgs, arg)
-							newArgs = append(newArgs, text)
-						})
-					}
-
-					_, _ = azcli.RunAzCommand(newArgs, nil)
-				}
-
-				return nil
-			})
+		return nil, fmt.Errorf("failed to create virtual machines client: %v", err)
+	}
 
+	vms := []*armcompute.VirtualMachine{}
+	ctx := context.Background()
+	var powerStates map[string]string
+	if resourceGroup == "" {
+		powerStates = virtualMachinePowerStates(vmClient)
+		vmPager := vmClient.NewListAllPager(nil)
+		for vmPager.More() {
+			page, err := vmPager.NextPage(ctx)
 			if err != nil {
-				out = fmt.Sprintf("Command execution failed with error: %v\n", err)
+				return nil, fmt.Errorf("failed to get next virtual machines page: %v", err)
 			}
-			if out == "" {
-				out = "Command executed successfully"
+			vms = append(vms, page.Value...)
+		}
+	} else {
+		vmPager := vmClient.NewListPager(resourceGroup, nil)
+		for vmPager.More() {
+			page, err := vmPager.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get next virtual machines page: %v", err)
 			}
+			vms = append(vms, page.Value...)
+		}
+		powerStates = resourceGroupPowerStates(vmClient, resourceGroup, vms)
+	}
 
-			output := tview.NewTextView()
-			v.Parent.AppendPrimitiveView(output, false, 0)
-			output.SetTitle("Command Output")
-			output.SetBorder(true)
-			output.Write([]byte(out))
+	items := []listItem{}
+	for _, vm := range vms {
+		items = append(items, listItem{
+			Name:      *vm.Name,
+			Secondary: *vm.Location,
+			ID:        *vm.ID,
+			State:     powerStates[strings.ToLower(*vm.ID)],
 		})
 	}
 
-	return cmdList
+	return items, nil
 }
 
-func (v *VirtualMachineListView) Update() error {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		return fmt.Errorf("failed to obtain a credential: %v", err)
+// scopeOf returns the subscription and resource group of the virtual
+// machine of item.
+func (v *VirtualMachineListView) scopeOf(item listItem) (string, string) {
+	if len(v.Subscriptions) == 0 {
+		return v.SubscriptionID, v.ResourceGroup
 	}
+	subscriptionID, resourceGroup, _ := item.scope()
+	return subscriptionID, resourceGroup
+}
 
-	v.List.Clear()
-	vmClient, err := armcompute.NewVirtualMachinesClient(v.SubscriptionID, cred, nil)
-	if err != nil {
-		log.Fatalf("failed to create virtual machines client: %v", err)
+// virtualMachinePowerStates returns the power state of every virtual
+// machine in the subscription, e.g. "running", keyed by lower case ID. Power
+// states are left out of the list when they can't be fetched.
+//
+// Only listings of the whole subscription use it, it reads every virtual
+// machine of the subscription whatever the resource group.
+func virtualMachinePowerStates(client *armcompute.VirtualMachinesClient) map[string]string {
+	states := map[string]string{}
+
+	pager := client.NewListAllPager(&armcompute.VirtualMachinesClientListAllOptions{StatusOnly: to.Ptr("true")})
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			logger.Warn("Failed to get virtual machine power states", "err", err)
+			return states
+		}
+
+		for _, vm := range page.Value {
+			if vm.ID == nil || vm.Properties == nil || vm.Properties.InstanceView == nil {
+				continue
+			}
+			if state := powerState(vm.Properties.InstanceView.Statuses); state != "" {
+				states[strings.ToLower(*vm.ID)] = state
+			}
+		}
 	}
 
-	vmPager := vmClient.NewListPager(v.ResourceGroup, nil)
-	for vmPager.More() {
-		ctx := context.Background()
-		page, err := vmPager.NextPage(ctx)
+	return states
+}
+
+// resourceGroupPowerStates returns the power states of vms, the virtual
+// machines of resourceGroup, like virtualMachinePowerStates. The compute API
+// version used can't list a resource group with instance views, so they are
+// fetched for each virtual machine, as many at once as subscriptions are
+// listed, see runBounded.
+func resourceGroupPowerStates(client *armcompute.VirtualMachinesClient, resourceGroup string, vms []*armcompute.VirtualMachine) map[string]string {
+	states := map[string]string{}
+
+	var mu sync.Mutex
+	runBounded(len(vms), func(i int) {
+		vm := vms[i]
+		if vm.ID == nil || vm.Name == nil {
+			return
+		}
+		view, err := client.InstanceView(context.Background(), resourceGroup, *vm.Name, nil)
 		if err != nil {
-			log.Fatalf("failed to get next virtual machines page: %v", err)
+			logger.Warn("Failed to get virtual machine power state", "vm", *vm.Name, "err", err)
+			return
 		}
-
-		if len(page.Value) == 0 && !vmPager.More() {
-			v.List.AddItem("(No VMs in resource group)", "", 0, nil)
+		if state := powerState(view.Statuses); state != "" {
+			mu.Lock()
+			states[strings.ToLower(*vm.ID)] = state
+			mu.Unlock()
 		}
+	})
 
-		for _, vm := range page.Value {
-			vmName := *vm.Name
-			vmLocation := *vm.Location
-			v.List.AddItem(vmName, vmLocation, 0, nil)
+	return states
+}
+
+// powerState returns the power state among statuses, e.g. "running", or ""
+// without one.
+func powerState(statuses []*armcompute.InstanceViewStatus) string {
+	for _, status := range statuses {
+		if status.Code != nil && strings.HasPrefix(*status.Code, "PowerState/") {
+			return strings.TrimPrefix(*status.Code, "PowerState/")
 		}
 	}
+	return ""
+}
+
+func (v *VirtualMachineListView) SpawnCommandListView() tview.Primitive {
+	return v.spawnCommandListView("SpawnCommandListView")
+}
+
+// spawnCommandListView lists the commands for the rows action, as bound in
+// the config, acts on.
+func (v *VirtualMachineListView) spawnCommandListView(action string) tview.Primitive {
+	targets := v.targets(action)
+	if len(targets) == 0 {
+		return nil
+	}
+	v.Parent.RemoveViewsAfter(v.List)
+	cmdList := NewBulkCommandListView(v.Parent, targets)
+
+	return cmdList.List
+}
+
+// targets returns the virtual machines action acts on, see listing.targets.
+func (v *VirtualMachineListView) targets(action string) []azcli.Target {
+	targets := []azcli.Target{}
+	for _, item := range v.listing.targets(action) {
+		targets = append(targets, v.target(item))
+	}
+	return targets
+}
+
+// target returns the virtual machine of item.
+func (v *VirtualMachineListView) target(item listItem) azcli.Target {
+	subscriptionID, resourceGroup := v.scopeOf(item)
+	return azcli.Target{
+		SubscriptionID: subscriptionID,
+		ResourceGroup:  resourceGroup,
+		Type:           "Microsoft.Compute/virtualMachines",
+		Name:           item.Name,
+	}
+}
+
+// CopyPortalLink copies the link to the selected virtual machine in the portal.
+func (v *VirtualMachineListView) CopyPortalLink() tview.Primitive {
+	if targets := v.targets("CopyPortalLink"); len(targets) > 0 {
+		v.Parent.CopyPortalLink(targets...)
+	}
 
 	return nil
 }
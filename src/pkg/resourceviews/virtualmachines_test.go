@@ -0,0 +1,72 @@
+package resourceviews
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+)
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// instanceViewTransport answers instance view requests with the virtual
+// machine running, tracking how many are answered at once.
+type instanceViewTransport struct {
+	concurrency
+}
+
+func (t *instanceViewTransport) Do(req *http.Request) (*http.Response, error) {
+	t.enter()
+	defer t.leave()
+	time.Sleep(10 * time.Millisecond)
+
+	body := `{"statuses": [{"code": "ProvisioningState/succeeded"}, {"code": "PowerState/running"}]}`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestResourceGroupPowerStatesBounded(t *testing.T) {
+	useConfig(t, "arm:\n  maxConcurrentSubscriptions: 3\n  maxConcurrentRequests: 0\n")
+
+	transport := &instanceViewTransport{}
+	client, err := armcompute.NewVirtualMachinesClient("sub", fakeCredential{}, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{Transport: transport},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vms := []*armcompute.VirtualMachine{}
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/VM%v", i)
+		vms = append(vms, &armcompute.VirtualMachine{ID: to.Ptr(id), Name: to.Ptr(fmt.Sprintf("VM%v", i))})
+	}
+
+	states := resourceGroupPowerStates(client, "rg", vms)
+	if len(states) != len(vms) {
+		t.Errorf("%v power states, want %v", len(states), len(vms))
+	}
+	if state := states["/subscriptions/sub/resourcegroups/rg/providers/microsoft.compute/virtualmachines/vm7"]; state != "running" {
+		t.Errorf("power state %q, want running", state)
+	}
+	if transport.max > 3 {
+		t.Errorf("%v power states fetched at once, want at most 3", transport.max)
+	}
+}
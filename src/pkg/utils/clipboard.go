@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Clipboard tools tried in order, the first one found on PATH is used
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// CopyToClipboard puts text on the system clipboard using whichever
+// clipboard tool is installed.
+func CopyToClipboard(text string) error {
+	commands := clipboardCommands
+	if runtime.GOOS == "windows" {
+		commands = [][]string{{"clip"}}
+	}
+
+	for _, command := range commands {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%v failed: %v", command[0], err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no clipboard tool found")
+}